@@ -50,10 +50,23 @@ func Apply(ctx context.Context, rcg genericclioptions.RESTClientGetter, opts *ru
 		return "", fmt.Errorf("no Kubernetes objects found at: %s", manifestPath)
 	}
 
-	if err := normalize.UnstructuredList(objs); err != nil {
+	changeSet, err := ApplyObjects(ctx, rcg, opts, objs)
+	if err != nil {
 		return "", err
 	}
 
+	return changeSet.String(), nil
+}
+
+// ApplyObjects applies the given objects with server-side apply. CRDs and Namespaces are applied first and
+// waited for, so objects of the new kinds and in the new namespaces can be applied afterwards.
+func ApplyObjects(
+	ctx context.Context, rcg genericclioptions.RESTClientGetter, opts *runclient.Options, objs []*unstructured.Unstructured,
+) (*ssa.ChangeSet, error) {
+	if err := normalize.UnstructuredList(objs); err != nil {
+		return nil, err
+	}
+
 	changeSet := ssa.NewChangeSet()
 
 	// contains only CRDs and Namespaces
@@ -73,24 +86,35 @@ func Apply(ctx context.Context, rcg genericclioptions.RESTClientGetter, opts *ru
 	if len(stageOne) > 0 {
 		cs, err := applySet(ctx, rcg, opts, stageOne)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		changeSet.Append(cs.Entries)
 	}
 
 	if err := waitForSet(rcg, opts, changeSet); err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if len(stageTwo) > 0 {
 		cs, err := applySet(ctx, rcg, opts, stageTwo)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		changeSet.Append(cs.Entries)
 	}
 
-	return changeSet.String(), nil
+	return changeSet, nil
+}
+
+// WaitForChangeSet waits until all objects of the change set are ready, as determined by kstatus.
+func WaitForChangeSet(
+	rcg genericclioptions.RESTClientGetter, opts *runclient.Options, changeSet *ssa.ChangeSet, timeout time.Duration,
+) error {
+	man, err := newManager(rcg, opts)
+	if err != nil {
+		return err
+	}
+	return man.WaitForSet(changeSet.ToObjMetadataSet(), ssa.WaitOptions{Interval: 2 * time.Second, Timeout: timeout})
 }
 
 func readObjects(root, manifestPath string) ([]*unstructured.Unstructured, error) {
@@ -0,0 +1,188 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package shared
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/e2e-framework/klient"
+	"sigs.k8s.io/e2e-framework/klient/conf"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/envfuncs"
+	"sigs.k8s.io/e2e-framework/support"
+	"sigs.k8s.io/e2e-framework/support/k3d"
+	"sigs.k8s.io/e2e-framework/support/kind"
+)
+
+// ClusterProvider names the way the test cluster is provided.
+type ClusterProvider string
+
+const (
+	// ClusterProviderKind creates a kind cluster.
+	ClusterProviderKind ClusterProvider = "kind"
+	// ClusterProviderK3d creates a k3d cluster.
+	ClusterProviderK3d ClusterProvider = "k3d"
+	// ClusterProviderExisting uses an existing cluster from the kubeconfig. It is neither created nor destroyed.
+	ClusterProviderExisting ClusterProvider = "existing"
+)
+
+// keepAliveClusterPrefix is prepended to the suite prefix to name clusters that are kept alive between runs.
+const keepAliveClusterPrefix = "ocm-e2e-"
+
+// Environment variables that select the cluster if the corresponding flag isn't set.
+const (
+	ClusterProviderEnv = "OCM_E2E_CLUSTER_PROVIDER"
+	ClusterNameEnv     = "OCM_E2E_CLUSTER_NAME"
+	KubeContextEnv     = "OCM_E2E_KUBE_CONTEXT"
+	KeepClusterEnv     = "OCM_E2E_KEEP_CLUSTER"
+)
+
+var (
+	clusterProviderFlag = flag.String("cluster-provider", "",
+		fmt.Sprintf("cluster provider to run the suite against: kind, k3d or existing (env %s)", ClusterProviderEnv))
+	clusterNameFlag = flag.String("cluster-name", "",
+		fmt.Sprintf("name of the cluster to create, a random name is used if empty (env %s)", ClusterNameEnv))
+	keepClusterFlag = flag.Bool("keep-cluster", false,
+		fmt.Sprintf("reuse the cluster of a previous run and leave it running afterwards (env %s)", KeepClusterEnv))
+)
+
+// ClusterOptions describes the cluster a suite runs against.
+type ClusterOptions struct {
+	Provider ClusterProvider
+	// Name of the kind or k3d cluster.
+	Name string
+	// ConfigFile is an optional provider specific configuration file, such as a kind cluster config.
+	ConfigFile string
+	// KubeContext is the context used by ClusterProviderExisting. If empty, the current context is used.
+	KubeContext string
+	// KeepAlive reuses a kind or k3d cluster with the same name if it exists and leaves the cluster running
+	// after the suite.
+	KeepAlive bool
+}
+
+// ClusterOptionsFromFlags returns the cluster options selected by the -cluster-provider and -cluster-name flags
+// or their environment variables. The context of an existing cluster is taken from the -context flag of
+// e2e-framework or OCM_E2E_KUBE_CONTEXT. It defaults to a kind cluster with a random name based on prefix.
+// With -keep-cluster or OCM_E2E_KEEP_CLUSTER the default name is stable, so the next run finds the cluster again.
+// It has to be called after the flags were parsed by envconf.NewFromFlags.
+func ClusterOptionsFromFlags(cfg *envconf.Config, prefix string) ClusterOptions {
+	opts := ClusterOptions{
+		Provider:    ClusterProvider(valueOrEnv(*clusterProviderFlag, ClusterProviderEnv)),
+		Name:        valueOrEnv(*clusterNameFlag, ClusterNameEnv),
+		KubeContext: valueOrEnv(cfg.KubeContext(), KubeContextEnv),
+		KeepAlive:   *keepClusterFlag,
+	}
+
+	if keep, err := strconv.ParseBool(os.Getenv(KeepClusterEnv)); err == nil && keep {
+		opts.KeepAlive = true
+	}
+
+	if opts.Provider == "" {
+		opts.Provider = ClusterProviderKind
+	}
+
+	if opts.Name == "" && opts.KeepAlive {
+		opts.Name = keepAliveClusterPrefix + prefix
+	}
+
+	if opts.Name == "" {
+		opts.Name = envconf.RandomName(prefix, 32)
+	}
+
+	return opts
+}
+
+// CreateCluster creates the cluster described by opts, or connects to it for ClusterProviderExisting, and
+// configures the environment to use it. A kind or k3d cluster that already exists with the same name is reused.
+func CreateCluster(opts ClusterOptions) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		if opts.Provider == ClusterProviderExisting {
+			return useExistingCluster(ctx, cfg, opts)
+		}
+
+		provider, err := clusterProvider(opts.Provider)
+		if err != nil {
+			return ctx, err
+		}
+
+		if opts.ConfigFile != "" {
+			return envfuncs.CreateClusterWithConfig(provider, opts.Name, opts.ConfigFile)(ctx, cfg)
+		}
+
+		return envfuncs.CreateCluster(provider, opts.Name)(ctx, cfg)
+	}
+}
+
+// DestroyCluster destroys the cluster created by CreateCluster. An existing cluster or a cluster with KeepAlive
+// set is left alone.
+func DestroyCluster(opts ClusterOptions) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		if opts.Provider == ClusterProviderExisting {
+			return ctx, nil
+		}
+
+		if opts.KeepAlive {
+			fmt.Printf("keeping %s cluster %s running\n", opts.Provider, opts.Name)
+
+			return ctx, nil
+		}
+
+		return envfuncs.DestroyCluster(opts.Name)(ctx, cfg)
+	}
+}
+
+func clusterProvider(provider ClusterProvider) (support.E2EClusterProvider, error) {
+	switch provider {
+	case ClusterProviderKind:
+		return kind.NewProvider(), nil
+	case ClusterProviderK3d:
+		return k3d.NewProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown cluster provider %q", provider)
+	}
+}
+
+// useExistingCluster points the environment at a cluster from the kubeconfig and checks that it's reachable.
+func useExistingCluster(ctx context.Context, cfg *envconf.Config, opts ClusterOptions) (context.Context, error) {
+	kubeconfig := cfg.KubeconfigFile()
+	if kubeconfig == "" {
+		kubeconfig = conf.ResolveKubeConfigFile()
+	}
+
+	restConfig, err := conf.NewWithContextName(kubeconfig, opts.KubeContext)
+	if err != nil {
+		return ctx, fmt.Errorf("failed to load kubeconfig %s with context %q: %w", kubeconfig, opts.KubeContext, err)
+	}
+
+	client, err := klient.New(restConfig)
+	if err != nil {
+		return ctx, fmt.Errorf("failed to create client for existing cluster: %w", err)
+	}
+
+	namespaces := &corev1.NamespaceList{}
+	if err := client.Resources().List(ctx, namespaces); err != nil {
+		return ctx, fmt.Errorf("failed to reach existing cluster with context %q: %w", opts.KubeContext, err)
+	}
+
+	cfg.WithKubeconfigFile(kubeconfig)
+	cfg.WithKubeContext(opts.KubeContext)
+	cfg.WithClient(client)
+
+	return ctx, nil
+}
+
+func valueOrEnv(value, key string) string {
+	if value != "" {
+		return value
+	}
+
+	return os.Getenv(key)
+}
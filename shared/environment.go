@@ -0,0 +1,283 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package shared
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/envfuncs"
+)
+
+const (
+	// DefaultNamespace is the namespace the controllers and Gitea are installed into.
+	DefaultNamespace = "ocm-system"
+
+	registryPort = 5000
+	giteaPort    = 3000
+)
+
+// EnvironmentOption configures the environment created by NewEnvironment.
+type EnvironmentOption func(*environmentOptions)
+
+// PortForwardSpec describes a port forward NewEnvironment starts. See ForwardPort.
+type PortForwardSpec struct {
+	Name      string
+	Target    PortForwardTarget
+	LocalPort int
+}
+
+// environmentStep pairs a setup function with the function that undoes it. Either may be nil.
+type environmentStep struct {
+	setup    env.Func
+	teardown env.Func
+}
+
+type environmentOptions struct {
+	cluster          *ClusterOptions
+	namespace        string
+	controllers      []string
+	releases         []ControllerRelease
+	gitea            bool
+	gitServerOptions []GitServerOption
+	forwards         []PortForwardSpec
+	extra            []environmentStep
+}
+
+// WithCluster uses the given cluster instead of the one selected by the flags. Suites that need the cluster
+// options, for example for RunVersionMatrix, create them with ClusterOptionsFromFlags and pass them here.
+func WithCluster(cluster ClusterOptions) EnvironmentOption {
+	return func(o *environmentOptions) {
+		o.cluster = &cluster
+	}
+}
+
+// WithNamespace sets the namespace the controllers and Gitea are installed into. Defaults to DefaultNamespace.
+func WithNamespace(namespace string) EnvironmentOption {
+	return func(o *environmentOptions) {
+		o.namespace = namespace
+	}
+}
+
+// WithControllers installs the given controllers using Tilt. See RunTiltForControllers.
+func WithControllers(controllers ...string) EnvironmentOption {
+	return func(o *environmentOptions) {
+		o.controllers = append(o.controllers, controllers...)
+	}
+}
+
+// WithControllerReleases installs released controllers at pinned versions instead of building them with Tilt.
+// Charts without a namespace are installed into the namespace set by WithNamespace. See InstallControllerReleases.
+func WithControllerReleases(releases ...ControllerRelease) EnvironmentOption {
+	return func(o *environmentOptions) {
+		o.releases = append(o.releases, releases...)
+	}
+}
+
+// WithGitea installs a Gitea server with the given options.
+func WithGitea(opts ...GitServerOption) EnvironmentOption {
+	return func(o *environmentOptions) {
+		o.gitea = true
+		o.gitServerOptions = append(o.gitServerOptions, opts...)
+	}
+}
+
+// WithPortForwards starts the given port forwards once everything else is installed. Targets without a namespace
+// are looked up in the namespace set by WithNamespace.
+func WithPortForwards(forwards ...PortForwardSpec) EnvironmentOption {
+	return func(o *environmentOptions) {
+		o.forwards = append(o.forwards, forwards...)
+	}
+}
+
+// WithSetup adds a custom setup function after the built-in ones. If teardown is not nil, it runs before the
+// teardown of everything set up earlier.
+func WithSetup(setup, teardown env.Func) EnvironmentOption {
+	return func(o *environmentOptions) {
+		o.extra = append(o.extra, environmentStep{setup: setup, teardown: teardown})
+	}
+}
+
+// RegistryPortForward forwards the in-cluster registry of the ocm-controller to 127.0.0.1:5000, where
+// AddComponentVersionToRepository expects it.
+func RegistryPortForward() PortForwardSpec {
+	return PortForwardSpec{
+		Name:      "registry",
+		Target:    PortForwardTarget{LabelSelector: map[string]string{"app": "registry"}, Port: registryPort},
+		LocalPort: registryPort,
+	}
+}
+
+// GiteaPortForward forwards the Gitea server to BaseURL.
+func GiteaPortForward() PortForwardSpec {
+	return PortForwardSpec{
+		Name:      "gitea",
+		Target:    PortForwardTarget{LabelSelector: map[string]string{"app": "gitea"}, Port: giteaPort},
+		LocalPort: giteaPort,
+	}
+}
+
+// NewEnvironment creates an environment for an OCM suite. It creates the cluster selected by the flags (see
+// ClusterOptionsFromFlags) using prefix for its name, the namespace, Gitea, the controllers and the port forwards,
+// in that order. Teardown functions are registered in reverse order, so port forwards are closed first and the
+// cluster is destroyed last. If the cluster is kept alive, Gitea and the controllers are only installed if their
+// deployments aren't available yet, and nothing but the port forwards is torn down.
+func NewEnvironment(prefix string, opts ...EnvironmentOption) (env.Environment, error) {
+	cfg, err := envconf.NewFromFlags()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse environment flags: %w", err)
+	}
+
+	options := &environmentOptions{namespace: DefaultNamespace}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	// release manifests come with their own namespace, only charts can be moved to another one
+	for i := range options.releases {
+		if options.releases[i].Namespace == "" && options.releases[i].Chart != "" {
+			options.releases[i].Namespace = options.namespace
+		}
+
+		if options.releases[i].Namespace == "" {
+			options.releases[i].Namespace = DefaultNamespace
+		}
+	}
+
+	cluster := ClusterOptionsFromFlags(cfg, prefix)
+	if options.cluster != nil {
+		cluster = *options.cluster
+	}
+	steps := []environmentStep{
+		{setup: CreateCluster(cluster), teardown: DestroyCluster(cluster)},
+		{setup: templateValues(map[string]any{"SystemNamespace": options.namespace})},
+	}
+
+	// a kept cluster keeps everything installed in it, so the next run only installs what's missing
+	if cluster.KeepAlive {
+		steps = append(steps, environmentStep{setup: ensureNamespace(options.namespace)})
+
+		if options.gitea {
+			steps = append(steps, environmentStep{
+				setup: unlessAvailable(options.namespace, []string{"gitea"}, StartGitServer(options.namespace, options.gitServerOptions...)),
+			})
+		}
+
+		if len(options.controllers) > 0 {
+			steps = append(steps, environmentStep{
+				setup: unlessAvailable(options.namespace, options.controllers, RunTiltForControllers(options.controllers...)),
+			})
+		}
+
+		for _, release := range options.releases {
+			steps = append(steps, environmentStep{
+				setup: unlessAvailable(release.Namespace, []string{release.Name}, InstallControllerReleases(cluster, release)),
+			})
+		}
+	} else {
+		steps = append(steps, environmentStep{
+			setup:    envfuncs.CreateNamespace(options.namespace),
+			teardown: envfuncs.DeleteNamespace(options.namespace),
+		})
+
+		if options.gitea {
+			steps = append(steps, environmentStep{
+				setup:    StartGitServer(options.namespace, options.gitServerOptions...),
+				teardown: RemoveGitServer(options.namespace, options.gitServerOptions...),
+			})
+		}
+
+		if len(options.controllers) > 0 {
+			steps = append(steps, environmentStep{setup: RunTiltForControllers(options.controllers...)})
+		}
+
+		if len(options.releases) > 0 {
+			steps = append(steps, environmentStep{setup: InstallControllerReleases(cluster, options.releases...)})
+		}
+	}
+
+	for _, forward := range options.forwards {
+		// the forwards of Gitea and the registry look for pods where they are installed
+		if forward.Target.Namespace == "" {
+			forward.Target.Namespace = options.namespace
+		}
+
+		steps = append(steps, environmentStep{setup: ForwardPort(forward.Name, forward.Target, forward.LocalPort)})
+	}
+
+	steps = append(steps, environmentStep{teardown: StopPortForwards()})
+	steps = append(steps, options.extra...)
+
+	testEnv := env.NewWithConfig(cfg)
+
+	for _, step := range steps {
+		if step.setup != nil {
+			testEnv.Setup(step.setup)
+		}
+	}
+
+	for i := len(steps) - 1; i >= 0; i-- {
+		if steps[i].teardown != nil {
+			testEnv.Finish(steps[i].teardown)
+		}
+	}
+
+	return testEnv, nil
+}
+
+// templateValues adds values for templated testdata to the context. See TemplateValues.
+func templateValues(values map[string]any) env.Func {
+	return func(ctx context.Context, _ *envconf.Config) (context.Context, error) {
+		return WithTemplateValues(ctx, values), nil
+	}
+}
+
+// ensureNamespace creates the namespace unless it already exists.
+func ensureNamespace(name string) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if err := cfg.Client().Resources().Create(ctx, namespace); err != nil && !apierrors.IsAlreadyExists(err) {
+			return ctx, fmt.Errorf("failed to create namespace %s: %w", name, err)
+		}
+
+		return ctx, nil
+	}
+}
+
+// unlessAvailable runs fn unless all the named deployments in namespace are available.
+func unlessAvailable(namespace string, deployments []string, fn env.Func) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		for _, name := range deployments {
+			deployment := &appsv1.Deployment{}
+			if err := cfg.Client().Resources().Get(ctx, name, namespace, deployment); err != nil {
+				return fn(ctx, cfg)
+			}
+
+			if !deploymentAvailable(deployment) {
+				return fn(ctx, cfg)
+			}
+		}
+
+		fmt.Printf("skipping installation, deployments %v are available in namespace %s\n", deployments, namespace)
+
+		return ctx, nil
+	}
+}
+
+func deploymentAvailable(deployment *appsv1.Deployment) bool {
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentAvailable {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
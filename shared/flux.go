@@ -5,13 +5,16 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/fluxcd/flux2/v2/pkg/manifestgen"
 	"github.com/fluxcd/flux2/v2/pkg/manifestgen/install"
 	runclient "github.com/fluxcd/pkg/runtime/client"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"sigs.k8s.io/e2e-framework/pkg/env"
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/yaml"
 
 	"github.com/open-component-model/ocm-e2e-framework/internal/utils"
 )
@@ -19,13 +22,37 @@ import (
 const (
 	maximumQueriesPerSecond = 50.0
 	burst                   = 300
+
+	fluxVersionLabel = "app.kubernetes.io/version"
+	fluxLatest       = "latest"
 )
 
-// InstallFlux creates a flux installation with a given version.
-func InstallFlux(version string) env.Func {
+// kustomization is the subset of a kustomization.yaml used to patch the generated Flux manifests.
+type kustomization struct {
+	APIVersion string               `json:"apiVersion"`
+	Kind       string               `json:"kind"`
+	Resources  []string             `json:"resources"`
+	Patches    []kustomizationPatch `json:"patches,omitempty"`
+}
+
+type kustomizationPatch struct {
+	Patch  string            `json:"patch"`
+	Target map[string]string `json:"target,omitempty"`
+}
+
+// InstallFlux creates a flux installation with a given version. It waits until the Deployment of every
+// installed controller is available and checks that the installed version is compatible with the requested one,
+// unless it's latest or WithoutFluxVersionCheck is given. The version is ignored if the manifests are given by
+// WithFluxManifests or WithFluxManifestFS, so Flux can be installed without network access.
+func InstallFlux(version string, opts ...FluxOption) env.Func {
 	// add files to cluster
 	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
-		// download version
+		options := newFluxOptions(version, opts...)
+
+		if err := utils.ValidateComponents(options.install.Components); err != nil {
+			return ctx, fmt.Errorf("invalid flux components: %w", err)
+		}
+
 		tmpDir, err := manifestgen.MkdirTempAbs("", "ocm-system")
 		if err != nil {
 			return ctx, err
@@ -33,29 +60,156 @@ func InstallFlux(version string) env.Func {
 
 		defer os.RemoveAll(tmpDir)
 
-		opts := install.MakeDefaultOptions()
-		opts.Version = version
-
-		manifest, err := install.Generate(opts, "")
+		manifestPath, components, err := fluxManifests(tmpDir, options)
 		if err != nil {
-			return ctx, fmt.Errorf("install generate failed: %w", err)
+			return ctx, err
 		}
 
-		if _, err := manifest.WriteFile(tmpDir); err != nil {
-			return ctx, fmt.Errorf("install write failed: %w", err)
+		if len(options.patches) > 0 {
+			if manifestPath, err = writeFluxKustomization(manifestPath, options.patches); err != nil {
+				return ctx, err
+			}
 		}
 
 		kubeConfig := cfg.KubeconfigFile()
 		kfg := genericclioptions.ConfigFlags{KubeConfig: &kubeConfig}
+
+		if cfg.KubeContext() != "" {
+			kubeContext := cfg.KubeContext()
+			kfg.Context = &kubeContext
+		}
+
 		runOpts := &runclient.Options{
 			QPS:   maximumQueriesPerSecond,
 			Burst: burst,
 		}
 
-		if _, err = utils.Apply(ctx, &kfg, runOpts, tmpDir, filepath.Join(tmpDir, manifest.Path)); err != nil {
+		if _, err = utils.Apply(ctx, &kfg, runOpts, tmpDir, manifestPath); err != nil {
 			return ctx, fmt.Errorf("install apply failed: %w", err)
 		}
 
+		if err := waitForDeployments(ctx, cfg, options.install.Namespace, components); err != nil {
+			return ctx, fmt.Errorf("flux controllers didn't become available: %w", err)
+		}
+
+		// a bundle can contain any version and latest is resolved by the generator
+		if !options.skipVersionCheck && options.manifests == nil && version != fluxLatest {
+			if err := checkFluxVersion(ctx, cfg, options.install.Namespace, version); err != nil {
+				return ctx, err
+			}
+		}
+
 		return ctx, nil
 	}
 }
+
+// fluxManifests writes the Flux manifests to dir and returns their path and the controllers they contain. The
+// manifests are taken from the bundle given by WithFluxManifests, or generated for the configured version. For a
+// bundle, the namespace of the options is set to the namespace of its controllers.
+func fluxManifests(dir string, options *fluxOptions) (string, []string, error) {
+	if options.manifests != nil {
+		return fluxBundleManifests(dir, options)
+	}
+
+	// download version
+	manifest, err := install.Generate(options.install, "")
+	if err != nil {
+		return "", nil, fmt.Errorf("install generate failed: %w", err)
+	}
+
+	if _, err := manifest.WriteFile(dir); err != nil {
+		return "", nil, fmt.Errorf("install write failed: %w", err)
+	}
+
+	return filepath.Join(dir, manifest.Path), options.install.Components, nil
+}
+
+func fluxBundleManifests(dir string, options *fluxOptions) (string, []string, error) {
+	if len(options.generatorOptions) > 0 {
+		return "", nil, fmt.Errorf("%s can't be used with flux manifests from a bundle",
+			strings.Join(options.generatorOptions, ", "))
+	}
+
+	manifestPath, deployments, err := writeFluxBundle(options.manifests, dir, options.imageMirror)
+	if err != nil {
+		return "", nil, err
+	}
+
+	components := make([]string, 0, len(deployments))
+
+	for _, deployment := range deployments {
+		if deployment.GetNamespace() != "" {
+			options.install.Namespace = deployment.GetNamespace()
+		}
+
+		components = append(components, deployment.GetName())
+	}
+
+	return manifestPath, components, nil
+}
+
+// writeFluxKustomization writes a kustomization.yaml next to the generated manifest that applies the patches
+// to it and returns its path.
+func writeFluxKustomization(manifestPath string, patches []FluxPatch) (string, error) {
+	k := kustomization{
+		APIVersion: "kustomize.config.k8s.io/v1beta1",
+		Kind:       "Kustomization",
+		Resources:  []string{filepath.Base(manifestPath)},
+	}
+
+	for _, patch := range patches {
+		p := kustomizationPatch{Patch: patch.Patch}
+		if patch.Kind != "" || patch.Name != "" {
+			p.Target = map[string]string{}
+		}
+
+		if patch.Kind != "" {
+			p.Target["kind"] = patch.Kind
+		}
+
+		if patch.Name != "" {
+			p.Target["name"] = patch.Name
+		}
+
+		k.Patches = append(k.Patches, p)
+	}
+
+	content, err := yaml.Marshal(k)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal flux kustomization: %w", err)
+	}
+
+	path := filepath.Join(filepath.Dir(manifestPath), "kustomization.yaml")
+
+	var kustomizationPermMod os.FileMode = 0o600
+	if err := os.WriteFile(path, content, kustomizationPermMod); err != nil {
+		return "", fmt.Errorf("failed to write flux kustomization: %w", err)
+	}
+
+	return path, nil
+}
+
+// checkFluxVersion checks that the version label of the Flux namespace is compatible with the requested version.
+func checkFluxVersion(ctx context.Context, cfg *envconf.Config, namespace, version string) error {
+	ns := &corev1.Namespace{}
+	if err := cfg.Client().Resources().Get(ctx, namespace, "", ns); err != nil {
+		return fmt.Errorf("failed to get flux namespace %s: %w", namespace, err)
+	}
+
+	installed, ok := ns.Labels[fluxVersionLabel]
+	if !ok {
+		return fmt.Errorf("flux namespace %s has no %s label", namespace, fluxVersionLabel)
+	}
+
+	return compatibleFluxVersion(version, installed)
+}
+
+// compatibleFluxVersion returns an error unless the installed version is compatible with the requested one,
+// see utils.CompatibleVersion.
+func compatibleFluxVersion(version, installed string) error {
+	if !utils.CompatibleVersion(version, installed) {
+		return fmt.Errorf("installed flux version %s is not compatible with the requested version %s", installed, version)
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package shared
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/distribution/reference"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	sigyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+const fluxBundleManifest = "gotk-components.yaml"
+
+// writeFluxBundle combines the manifests of a Flux bundle into a single file in dir, rewriting the controller
+// images to mirror if it's set. It returns the path of the file and the Deployments in it.
+func writeFluxBundle(fsys fs.FS, dir, mirror string) (string, []*unstructured.Unstructured, error) {
+	var files []string
+
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to list flux manifests: %w", err)
+		}
+
+		files = append(files, matches...)
+	}
+
+	if len(files) == 0 {
+		return "", nil, errors.New("no flux manifests found in bundle")
+	}
+
+	sort.Strings(files)
+
+	var (
+		out         bytes.Buffer
+		deployments []*unstructured.Unstructured
+	)
+
+	for _, file := range files {
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read flux manifest %s: %w", file, err)
+		}
+
+		found, err := appendFluxManifest(&out, content, mirror)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to decode flux manifest %s: %w", file, err)
+		}
+
+		deployments = append(deployments, found...)
+	}
+
+	manifestPath := filepath.Join(dir, fluxBundleManifest)
+
+	var manifestPermMod os.FileMode = 0o600
+	if err := os.WriteFile(manifestPath, out.Bytes(), manifestPermMod); err != nil {
+		return "", nil, fmt.Errorf("failed to write flux manifest: %w", err)
+	}
+
+	return manifestPath, deployments, nil
+}
+
+// appendFluxManifest appends the objects of a manifest to out and returns its Deployments.
+func appendFluxManifest(out *bytes.Buffer, content []byte, mirror string) ([]*unstructured.Unstructured, error) {
+	objects, err := decodeObjects(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var deployments []*unstructured.Unstructured
+
+	for _, obj := range objects {
+		if obj.GetKind() == "Deployment" {
+			deployments = append(deployments, obj)
+
+			if mirror != "" {
+				if err := rewriteImages(obj, mirror); err != nil {
+					return nil, fmt.Errorf("failed to rewrite images of %s: %w", obj.GetName(), err)
+				}
+			}
+		}
+
+		y, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s: %w", obj.GetName(), err)
+		}
+
+		out.WriteString("---\n")
+		out.Write(y)
+	}
+
+	return deployments, nil
+}
+
+// decodeObjects decodes the objects of a multi document YAML or JSON manifest, skipping empty documents.
+func decodeObjects(content []byte) ([]*unstructured.Unstructured, error) {
+	var objects []*unstructured.Unstructured
+
+	reader := sigyaml.NewYAMLOrJSONDecoder(bytes.NewReader(content), 2048)
+
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := reader.Decode(&obj.Object); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if len(obj.Object) > 0 {
+			objects = append(objects, obj)
+		}
+	}
+
+	return objects, nil
+}
+
+// rewriteImages replaces the registry and repository of the container images of a Deployment with mirror,
+// keeping the image name, tag and digest. ghcr.io/fluxcd/source-controller:v1.0.0 becomes
+// <mirror>/source-controller:v1.0.0.
+func rewriteImages(deployment *unstructured.Unstructured, mirror string) error {
+	for _, field := range []string{"initContainers", "containers"} {
+		fields := []string{"spec", "template", "spec", field}
+
+		containers, found, err := unstructured.NestedSlice(deployment.Object, fields...)
+		if err != nil {
+			return err
+		}
+
+		if !found {
+			continue
+		}
+
+		for _, c := range containers {
+			container, ok := c.(map[string]any)
+			if !ok {
+				continue
+			}
+
+			if image, ok := container["image"].(string); ok {
+				mirrored, err := mirrorImage(image, mirror)
+				if err != nil {
+					return err
+				}
+
+				container["image"] = mirrored
+			}
+		}
+
+		if err := unstructured.SetNestedSlice(deployment.Object, containers, fields...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mirrorImage returns the reference of image in mirror. Only the last element of the repository path is kept,
+// like for the registry option of flux install.
+func mirrorImage(image, mirror string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse image %s: %w", image, err)
+	}
+
+	mirrored := mirror + "/" + path.Base(reference.Path(named))
+
+	if tagged, ok := named.(reference.Tagged); ok {
+		mirrored += ":" + tagged.Tag()
+	}
+
+	if digested, ok := named.(reference.Digested); ok {
+		mirrored += "@" + digested.Digest().String()
+	}
+
+	if _, err := reference.ParseNormalizedNamed(mirrored); err != nil {
+		return "", fmt.Errorf("invalid mirrored image %s: %w", mirrored, err)
+	}
+
+	return mirrored, nil
+}
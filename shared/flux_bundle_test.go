@@ -0,0 +1,173 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package shared
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const testDigest = "sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+
+func TestMirrorImage(t *testing.T) {
+	tests := []struct {
+		name     string
+		image    string
+		mirror   string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "tag",
+			image:    "ghcr.io/fluxcd/source-controller:v1.0.0",
+			mirror:   "registry.local:5000/fluxcd",
+			expected: "registry.local:5000/fluxcd/source-controller:v1.0.0",
+		},
+		{
+			name:     "digest",
+			image:    "ghcr.io/fluxcd/source-controller@" + testDigest,
+			mirror:   "registry.local:5000/fluxcd",
+			expected: "registry.local:5000/fluxcd/source-controller@" + testDigest,
+		},
+		{
+			name:     "tag and digest",
+			image:    "ghcr.io/fluxcd/source-controller:v1.0.0@" + testDigest,
+			mirror:   "registry.local:5000/fluxcd",
+			expected: "registry.local:5000/fluxcd/source-controller:v1.0.0@" + testDigest,
+		},
+		{
+			name:     "nested repository",
+			image:    "registry.example.com/mirrors/fluxcd/kustomize-controller:v1.1.0",
+			mirror:   "localhost:5000",
+			expected: "localhost:5000/kustomize-controller:v1.1.0",
+		},
+		{
+			name:     "registry with port",
+			image:    "localhost:5000/helm-controller:v0.36.0",
+			mirror:   "mirror.local/fluxcd",
+			expected: "mirror.local/fluxcd/helm-controller:v0.36.0",
+		},
+		{
+			name:     "docker hub short name",
+			image:    "alpine",
+			mirror:   "mirror.local",
+			expected: "mirror.local/alpine",
+		},
+		{
+			name:    "invalid image",
+			image:   "ghcr.io/fluxcd/Source-Controller:v1.0.0",
+			mirror:  "mirror.local",
+			wantErr: true,
+		},
+		{
+			name:    "invalid mirror",
+			image:   "ghcr.io/fluxcd/source-controller:v1.0.0",
+			mirror:  "Mirror.local/FluxCD",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := mirrorImage(tt.image, tt.mirror)
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestRewriteImages(t *testing.T) {
+	deployment := &unstructured.Unstructured{Object: map[string]any{
+		"kind": "Deployment",
+		"spec": map[string]any{"template": map[string]any{"spec": map[string]any{
+			"initContainers": []any{
+				map[string]any{"name": "init", "image": "ghcr.io/fluxcd/init:v1.0.0"},
+			},
+			"containers": []any{
+				map[string]any{"name": "manager", "image": "ghcr.io/fluxcd/source-controller:v1.0.0@" + testDigest},
+			},
+		}}},
+	}}
+
+	require.NoError(t, rewriteImages(deployment, "mirror.local/fluxcd"))
+	assert.Equal(t, []string{
+		"mirror.local/fluxcd/init:v1.0.0",
+		"mirror.local/fluxcd/source-controller:v1.0.0@" + testDigest,
+	}, containerImages(deployment))
+}
+
+func TestWriteFluxBundle(t *testing.T) {
+	bundle := fstest.MapFS{
+		"b-controllers.yaml": {Data: []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: source-controller
+  namespace: flux
+spec:
+  template:
+    spec:
+      containers:
+        - name: manager
+          image: ghcr.io/fluxcd/source-controller:v1.0.0
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: kustomize-controller
+  namespace: flux
+spec:
+  template:
+    spec:
+      containers:
+        - name: manager
+          image: ghcr.io/fluxcd/kustomize-controller:v1.0.0
+`)},
+		"a-namespace.yml": {Data: []byte(`apiVersion: v1
+kind: Namespace
+metadata:
+  name: flux
+`)},
+		"README.md": {Data: []byte("not a manifest")},
+	}
+
+	manifestPath, deployments, err := writeFluxBundle(bundle, t.TempDir(), "mirror.local")
+	require.NoError(t, err)
+
+	objects, err := readObjectsFromFile(manifestPath)
+	require.NoError(t, err)
+
+	kinds := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		kinds = append(kinds, obj.GetKind())
+	}
+
+	// files are combined in the order of their names
+	assert.Equal(t, []string{"Namespace", "Deployment", "Deployment"}, kinds)
+	require.Len(t, deployments, 2)
+	assert.Equal(t, "source-controller", deployments[0].GetName())
+	assert.Equal(t, "flux", deployments[0].GetNamespace())
+	assert.Equal(t, []string{"mirror.local/kustomize-controller:v1.0.0"}, containerImages(objects[2]))
+
+	_, _, err = writeFluxBundle(fstest.MapFS{"README.md": {Data: []byte("")}}, t.TempDir(), "")
+	assert.Error(t, err)
+}
+
+func TestFluxBundleManifestsRejectsGeneratorOptions(t *testing.T) {
+	options := newFluxOptions("", WithFluxManifestFS(fstest.MapFS{}), WithFluxNamespace("flux"), WithFluxNetworkPolicy(false))
+
+	_, _, err := fluxManifests(t.TempDir(), options)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "WithFluxNamespace, WithFluxNetworkPolicy")
+}
@@ -0,0 +1,120 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package shared
+
+import (
+	"io/fs"
+	"os"
+
+	"github.com/fluxcd/flux2/v2/pkg/manifestgen/install"
+)
+
+// FluxOption customizes the Flux installation created by InstallFlux.
+type FluxOption func(*fluxOptions)
+
+// FluxPatch is a strategic merge or JSON 6902 patch applied to the generated Flux manifests, the same way as
+// the patches of a kustomization.yaml. Kind and Name select the patched objects. If both are empty, the patch
+// selects its targets itself.
+type FluxPatch struct {
+	Kind  string
+	Name  string
+	Patch string
+}
+
+type fluxOptions struct {
+	install          install.Options
+	patches          []FluxPatch
+	skipVersionCheck bool
+	manifests        fs.FS
+	imageMirror      string
+	deleteCRDs       bool
+	// generatorOptions are the options given that only apply to generated manifests, not to a bundle.
+	generatorOptions []string
+}
+
+func newFluxOptions(version string, opts ...FluxOption) *fluxOptions {
+	options := &fluxOptions{install: install.MakeDefaultOptions()}
+	options.install.Version = version
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return options
+}
+
+// WithFluxComponents installs only the given Flux controllers, for example "source-controller" and
+// "kustomize-controller". Extra components such as "image-reflector-controller" can be selected as well.
+func WithFluxComponents(components ...string) FluxOption {
+	return func(o *fluxOptions) {
+		o.install.Components = components
+		o.generatorOptions = append(o.generatorOptions, "WithFluxComponents")
+	}
+}
+
+// WithFluxNamespace installs Flux into the given namespace instead of flux-system.
+func WithFluxNamespace(namespace string) FluxOption {
+	return func(o *fluxOptions) {
+		o.install.Namespace = namespace
+		o.generatorOptions = append(o.generatorOptions, "WithFluxNamespace")
+	}
+}
+
+// WithFluxNetworkPolicy enables or disables the network policies that deny ingress to the Flux controllers
+// from other namespaces. They are enabled by default.
+func WithFluxNetworkPolicy(enabled bool) FluxOption {
+	return func(o *fluxOptions) {
+		o.install.NetworkPolicy = enabled
+		o.generatorOptions = append(o.generatorOptions, "WithFluxNetworkPolicy")
+	}
+}
+
+// WithFluxPatches applies the given patches to the generated manifests before installing them.
+func WithFluxPatches(patches ...FluxPatch) FluxOption {
+	return func(o *fluxOptions) {
+		o.patches = append(o.patches, patches...)
+	}
+}
+
+// WithoutFluxVersionCheck skips checking that the installed Flux version matches the requested one, for example
+// if a patch changes the version label.
+func WithoutFluxVersionCheck() FluxOption {
+	return func(o *fluxOptions) {
+		o.skipVersionCheck = true
+	}
+}
+
+// WithFluxManifests installs Flux from the manifests in dir instead of downloading them. The directory
+// contains plain manifests, such as the output of flux install --export. All *.yaml and *.yml files in it
+// are installed. The namespace and the controllers are taken from the manifests, so WithFluxComponents,
+// WithFluxNamespace and WithFluxNetworkPolicy can't be combined with it.
+func WithFluxManifests(dir string) FluxOption {
+	return WithFluxManifestFS(os.DirFS(dir))
+}
+
+// WithFluxManifestFS installs Flux from the manifests in fsys, for example an embed.FS bundled with a suite.
+// See WithFluxManifests.
+func WithFluxManifestFS(fsys fs.FS) FluxOption {
+	return func(o *fluxOptions) {
+		o.manifests = fsys
+	}
+}
+
+// WithFluxImageMirror pulls the controller images from the given registry instead of ghcr.io/fluxcd, for
+// example "registry.local:5000/fluxcd". The images of manifests given by WithFluxManifests are rewritten.
+func WithFluxImageMirror(registry string) FluxOption {
+	return func(o *fluxOptions) {
+		o.install.Registry = registry
+		o.imageMirror = registry
+	}
+}
+
+// WithFluxCRDDeletion makes UninstallFlux delete the Flux custom resources in all namespaces and the Flux CRDs as
+// well. Without it, only the custom resources in the Flux namespace are deleted.
+func WithFluxCRDDeletion() FluxOption {
+	return func(o *fluxOptions) {
+		o.deleteCRDs = true
+	}
+}
@@ -0,0 +1,62 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package shared
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompatibleFluxVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		version   string
+		installed string
+		wantErr   bool
+	}{
+		{
+			name:      "same version",
+			version:   "v2.3.0",
+			installed: "v2.3.0",
+		},
+		{
+			name:      "compatible patch version",
+			version:   "v2.3.0",
+			installed: "v2.3.1",
+		},
+		{
+			name:      "incompatible minor version",
+			version:   "v2.3.0",
+			installed: "v2.2.3",
+			wantErr:   true,
+		},
+		{
+			name:      "incompatible major version",
+			version:   "v2.3.0",
+			installed: "v0.41.2",
+			wantErr:   true,
+		},
+		{
+			name:      "invalid installed version",
+			version:   "v2.3.0",
+			installed: "unknown",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := compatibleFluxVersion(tt.version, tt.installed)
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
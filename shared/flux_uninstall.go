@@ -0,0 +1,205 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package shared
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+
+	"github.com/open-component-model/ocm-e2e-framework/internal/utils"
+)
+
+// UninstallFlux removes a Flux installation created by InstallFlux. It has to be called with the same version
+// and manifest options. The Flux custom resources in the Flux namespace are deleted first, while the controllers
+// can still remove their finalizers, otherwise the namespace couldn't be deleted. With WithFluxCRDDeletion, the
+// custom resources in all namespaces are deleted, and the CRDs are deleted after the controllers.
+func UninstallFlux(version string, opts ...FluxOption) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		options := newFluxOptions(version, opts...)
+
+		tmpDir, err := os.MkdirTemp("", "flux-uninstall")
+		if err != nil {
+			return ctx, fmt.Errorf("failed to create temp folder: %w", err)
+		}
+
+		defer os.RemoveAll(tmpDir)
+
+		manifestPath, _, err := fluxManifests(tmpDir, options)
+		if err != nil {
+			return ctx, err
+		}
+
+		objects, err := readObjectsFromFile(manifestPath)
+		if err != nil {
+			return ctx, err
+		}
+
+		// objects in other namespaces are kept together with the CRDs
+		crNamespace := options.install.Namespace
+		if options.deleteCRDs {
+			crNamespace = ""
+		}
+
+		crds, err := deleteFluxCustomResources(ctx, cfg, manifestPath, crNamespace)
+		if err != nil {
+			return ctx, err
+		}
+
+		if !options.deleteCRDs {
+			crds = nil
+		}
+
+		var namespaces []*unstructured.Unstructured
+
+		// delete in reverse order, so the controllers are gone before their RBAC
+		for i := len(objects) - 1; i >= 0; i-- {
+			switch objects[i].GetKind() {
+			case "CustomResourceDefinition":
+				continue
+			case "Namespace":
+				namespaces = append(namespaces, objects[i])
+
+				continue
+			}
+
+			if err := deleteIfExists(ctx, cfg, objects[i]); err != nil {
+				return ctx, err
+			}
+		}
+
+		for _, obj := range append(crds, namespaces...) {
+			if err := deleteIfExists(ctx, cfg, obj); err != nil {
+				return ctx, err
+			}
+
+			if err := wait.For(
+				conditions.New(cfg.Client().Resources()).ResourceDeleted(obj),
+				wait.WithTimeout(timeout),
+			); err != nil {
+				return ctx, fmt.Errorf("%s %s wasn't deleted: %w", obj.GetKind(), obj.GetName(), err)
+			}
+		}
+
+		return ctx, nil
+	}
+}
+
+// deleteFluxCustomResources deletes the objects of all CRDs in the Flux manifest in namespace, or in all namespaces
+// if it's empty, and returns the CRDs.
+func deleteFluxCustomResources(
+	ctx context.Context, cfg *envconf.Config, manifestPath, namespace string,
+) ([]*unstructured.Unstructured, error) {
+	crdPath := filepath.Join(filepath.Dir(manifestPath), "crds.yaml")
+	if err := utils.ExtractCRDs(manifestPath, crdPath); err != nil {
+		return nil, fmt.Errorf("failed to extract flux crds: %w", err)
+	}
+
+	crds, err := readObjectsFromFile(crdPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, crd := range crds {
+		if err := deleteCustomResources(ctx, cfg, crd, namespace); err != nil {
+			return nil, err
+		}
+	}
+
+	return crds, nil
+}
+
+// deleteCustomResources deletes all objects of the given CRD in namespace, or in all namespaces if it's empty,
+// and waits until their finalizers have run. It does nothing if the CRD isn't installed.
+func deleteCustomResources(ctx context.Context, cfg *envconf.Config, crd *unstructured.Unstructured, namespace string) error {
+	gvk, err := storageVersionKind(crd)
+	if err != nil {
+		return err
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+
+	if err := cfg.Client().Resources(namespace).List(ctx, list); err != nil {
+		if meta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+			return nil
+		}
+
+		return fmt.Errorf("failed to list %s: %w", gvk.Kind, err)
+	}
+
+	for i := range list.Items {
+		if err := deleteIfExists(ctx, cfg, &list.Items[i]); err != nil {
+			return err
+		}
+	}
+
+	if err := wait.For(
+		conditions.New(cfg.Client().Resources()).ResourcesDeleted(list),
+		wait.WithTimeout(timeout),
+	); err != nil {
+		return fmt.Errorf("%s objects weren't deleted: %w", gvk.Kind, err)
+	}
+
+	return nil
+}
+
+// storageVersionKind returns the group, storage version and kind of the objects defined by a CRD.
+func storageVersionKind(crd *unstructured.Unstructured) (schema.GroupVersionKind, error) {
+	group, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+	kind, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "kind")
+
+	versions, _, err := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	if err != nil {
+		return schema.GroupVersionKind{}, fmt.Errorf("failed to read versions of crd %s: %w", crd.GetName(), err)
+	}
+
+	for _, v := range versions {
+		version, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		if storage, _ := version["storage"].(bool); storage {
+			name, _ := version["name"].(string)
+
+			return schema.GroupVersionKind{Group: group, Version: name, Kind: kind}, nil
+		}
+	}
+
+	return schema.GroupVersionKind{}, fmt.Errorf("crd %s has no storage version", crd.GetName())
+}
+
+func deleteIfExists(ctx context.Context, cfg *envconf.Config, obj *unstructured.Unstructured) error {
+	if err := cfg.Client().Resources().Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+		return fmt.Errorf("failed to delete %s %s: %w", obj.GetKind(), obj.GetName(), err)
+	}
+
+	return nil
+}
+
+func readObjectsFromFile(path string) ([]*unstructured.Unstructured, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	objects, err := decodeObjects(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	return objects, nil
+}
@@ -6,13 +6,18 @@ package shared
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"sort"
+	"sync"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/tools/portforward"
 	"k8s.io/client-go/transport/spdy"
@@ -24,111 +29,617 @@ import (
 const (
 	defaultPortForwardReadyWaitTime = 10
 	timeoutDuration                 = time.Minute * 2
+	portForwardReconnectInterval    = time.Second * 2
 )
 
-// PortForward forwards the given port for the given pod name.
-func PortForward(port int, stopChannel chan struct{}, podName string, ctx context.Context, config *envconf.Config) (context.Context, error) {
-	transport, upgrader, err := spdy.RoundTripperFor(config.Client().RESTConfig())
+// PortForwardTarget selects the pod a port forward connects to. It is resolved again every time the
+// forward reconnects, so a replaced pod is picked up automatically.
+//
+// The pod is selected by Service, PodName or LabelSelector, in that order of precedence, and one of them
+// is required. The port is either given as a number in Port or by name in PortName. For a Service, these
+// refer to the service port and are translated to the target port of the selected endpoint.
+type PortForwardTarget struct {
+	// Namespace of the pod or service. If empty, the namespace of the environment config is used.
+	Namespace string
+	// Service forwards to a ready endpoint of the service with this name.
+	Service string
+	// PodName selects a single pod by name.
+	PodName string
+	// LabelSelector selects the pod by labels. If multiple running pods match, the newest is used.
+	LabelSelector map[string]string
+	// Port is the port in the pod, or of the service, to forward to.
+	Port int
+	// PortName is the name of the container port, or of the service port, to forward to.
+	PortName string
+}
+
+// PortForwarder keeps a port forward to a pod open. If the connection drops, for example because the pod
+// was replaced, the target is resolved again and the forward reconnects on the same local port. Errors are
+// recorded instead of terminating the test binary.
+type PortForwarder struct {
+	name      string
+	config    *envconf.Config
+	target    PortForwardTarget
+	localPort int
+
+	mu       sync.Mutex
+	errs     []error
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// NewPortForwarder creates a port forwarder for the given target. If localPort is 0, a free local port
+// is chosen when the forwarder starts.
+func NewPortForwarder(name string, config *envconf.Config, target PortForwardTarget, localPort int) *PortForwarder {
+	return &PortForwarder{
+		name:      name,
+		config:    config,
+		target:    target,
+		localPort: localPort,
+		stopCh:    make(chan struct{}),
+		doneCh:    make(chan struct{}),
+	}
+}
+
+// Name returns the name of the port forward.
+func (f *PortForwarder) Name() string {
+	return f.name
+}
+
+// LocalPort returns the local port the forward listens on.
+func (f *PortForwarder) LocalPort() int {
+	return f.localPort
+}
+
+// Address returns the local host:port address the forward listens on.
+func (f *PortForwarder) Address() string {
+	return net.JoinHostPort("127.0.0.1", fmt.Sprintf("%d", f.localPort))
+}
+
+// Errors returns all errors that occurred while forwarding, including those that led to a reconnect.
+func (f *PortForwarder) Errors() []error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	errs := make([]error, len(f.errs))
+	copy(errs, f.errs)
+
+	return errs
+}
+
+// Start opens the port forward and waits until it is ready.
+func (f *PortForwarder) Start(ctx context.Context) error {
+	if f.localPort == 0 {
+		port, err := freeLocalPort()
+		if err != nil {
+			return err
+		}
+
+		f.localPort = port
+	}
+
+	ready := make(chan error, 1)
+
+	go f.run(ctx, ready)
+
+	tctx, cancel := context.WithTimeout(ctx, defaultPortForwardReadyWaitTime*time.Second)
+	defer cancel()
+
+	select {
+	case err := <-ready:
+		if err != nil {
+			return fmt.Errorf("failed to start port forwarder %s: %w", f.name, err)
+		}
+	case <-tctx.Done():
+		f.Stop()
+
+		return fmt.Errorf("failed to start port forwarder %s: %w", f.name, tctx.Err())
+	}
+
+	return nil
+}
+
+// Stop closes the port forward and waits for it to shut down. It is safe to call Stop multiple times.
+func (f *PortForwarder) Stop() {
+	f.stopOnce.Do(func() {
+		close(f.stopCh)
+	})
+
+	<-f.doneCh
+}
+
+// run keeps the port forward connected until Stop is called. The outcome of the first connection
+// attempt is reported on ready.
+func (f *PortForwarder) run(ctx context.Context, ready chan<- error) {
+	defer close(f.doneCh)
+
+	connected := false
+
+	for {
+		err := f.forward(ctx, func() {
+			if !connected {
+				connected = true
+				ready <- nil
+
+				return
+			}
+
+			fmt.Printf("port forward %s reconnected on %s\n", f.name, f.Address())
+		})
+		if err == nil {
+			// stopped
+			return
+		}
+
+		f.recordError(err)
+
+		if !connected {
+			ready <- err
+
+			return
+		}
+
+		fmt.Printf("port forward %s lost connection, reconnecting: %s\n", f.name, err)
+
+		select {
+		case <-f.stopCh:
+			return
+		case <-time.After(portForwardReconnectInterval):
+		}
+	}
+}
+
+// forward opens a single connection and blocks until it drops or the forwarder is stopped. It returns
+// nil only if the forwarder was stopped.
+func (f *PortForwarder) forward(ctx context.Context, onReady func()) error {
+	target, err := f.resolve(ctx)
+	if err != nil {
+		return err
+	}
+
+	transport, upgrader, err := spdy.RoundTripperFor(f.config.Client().RESTConfig())
 	if err != nil {
-		return ctx, fmt.Errorf("failed to process round tripper: %w", err)
+		return fmt.Errorf("failed to process round tripper: %w", err)
 	}
-	readyChannel := make(chan struct{})
 
 	reqURL, err := url.Parse(
 		fmt.Sprintf(
 			"%s/api/v1/namespaces/%s/pods/%s/portforward",
-			config.Client().RESTConfig().Host,
-			config.Namespace(),
-			podName,
+			f.config.Client().RESTConfig().Host,
+			target.namespace,
+			target.pod,
 		),
 	)
 	if err != nil {
-		return ctx, fmt.Errorf("could not build URL for portforward: %w", err)
+		return fmt.Errorf("could not build URL for portforward: %w", err)
 	}
 
 	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", reqURL)
+	readyCh := make(chan struct{})
+	connStopCh := make(chan struct{})
 
 	fw, err := portforward.NewOnAddresses(
 		dialer,
 		[]string{"127.0.0.1"},
-		[]string{fmt.Sprintf("%d:%d", port, port)},
-		stopChannel,
-		readyChannel,
+		[]string{fmt.Sprintf("%d:%d", f.localPort, target.port)},
+		connStopCh,
+		readyCh,
 		os.Stdout,
 		os.Stderr,
 	)
 	if err != nil {
-		return ctx, fmt.Errorf("failed to create port forwarder: %w", err)
+		return fmt.Errorf("failed to create port forwarder: %w", err)
 	}
 
+	errCh := make(chan error, 1)
+
 	go func() {
-		if err := fw.ForwardPorts(); err != nil {
-			panic(err)
-		}
+		errCh <- fw.ForwardPorts()
 	}()
 
-	tctx, cancel := context.WithTimeout(ctx, defaultPortForwardReadyWaitTime*time.Second)
-	defer cancel()
+	select {
+	case <-readyCh:
+		onReady()
+	case err := <-errCh:
+		return forwardError(err)
+	case <-f.stopCh:
+		close(connStopCh)
+		<-errCh
+
+		return nil
+	}
 
 	select {
-	case <-readyChannel:
-		break
-	case <-tctx.Done():
-		return ctx, fmt.Errorf("failed to start port forwarder: %w", ctx.Err())
+	case err := <-errCh:
+		return forwardError(err)
+	case <-f.stopCh:
+		close(connStopCh)
+		<-errCh
+
+		return nil
+	}
+}
+
+// resolvedTarget is the pod and port a single connection of a port forward uses.
+type resolvedTarget struct {
+	namespace string
+	pod       string
+	port      int
+}
+
+// resolve returns the pod and port to forward to.
+func (f *PortForwarder) resolve(ctx context.Context) (resolvedTarget, error) {
+	target := resolvedTarget{
+		namespace: f.target.Namespace,
+		port:      f.target.Port,
+	}
+
+	// an empty selector matches every pod in the namespace
+	if f.target.Service == "" && f.target.PodName == "" && len(f.target.LabelSelector) == 0 {
+		return target, fmt.Errorf("port forward %s has no service, pod name or label selector", f.name)
+	}
+
+	if target.namespace == "" {
+		target.namespace = f.config.Namespace()
+	}
+
+	r, err := resources.New(f.config.Client().RESTConfig())
+	if err != nil {
+		return target, fmt.Errorf("failed to create resource client: %w", err)
+	}
+
+	if err := v1.AddToScheme(r.GetScheme()); err != nil {
+		return target, fmt.Errorf("failed to add schema to resource client: %w", err)
+	}
+
+	if err := discoveryv1.AddToScheme(r.GetScheme()); err != nil {
+		return target, fmt.Errorf("failed to add schema to resource client: %w", err)
+	}
+
+	r = r.WithNamespace(target.namespace)
+
+	if f.target.Service != "" {
+		return f.resolveService(ctx, r, target)
+	}
+
+	var pod *v1.Pod
+
+	if f.target.PodName != "" {
+		pod = &v1.Pod{}
+		if err := r.Get(ctx, f.target.PodName, target.namespace, pod); err != nil {
+			return target, fmt.Errorf("failed to get pod %s/%s: %w", target.namespace, f.target.PodName, err)
+		}
+	} else {
+		pod, err = newestRunningPod(ctx, r, target.namespace, f.target.LabelSelector)
+		if err != nil {
+			return target, err
+		}
+	}
+
+	target.pod = pod.Name
+
+	if f.target.PortName != "" {
+		port, err := containerPortByName(pod, f.target.PortName)
+		if err != nil {
+			return target, err
+		}
+
+		target.port = port
+	}
+
+	return target, nil
+}
+
+// resolveService picks a ready endpoint of the target service and translates the service port to the
+// port of the endpoint.
+func (f *PortForwarder) resolveService(ctx context.Context, r *resources.Resources, target resolvedTarget) (resolvedTarget, error) {
+	service := &v1.Service{}
+	if err := r.Get(ctx, f.target.Service, target.namespace, service); err != nil {
+		return target, fmt.Errorf("failed to get service %s/%s: %w", target.namespace, f.target.Service, err)
+	}
+
+	var servicePort *v1.ServicePort
+
+	for i, port := range service.Spec.Ports {
+		if (f.target.PortName != "" && port.Name == f.target.PortName) ||
+			(f.target.PortName == "" && int(port.Port) == f.target.Port) {
+			servicePort = &service.Spec.Ports[i]
+
+			break
+		}
+	}
+
+	if servicePort == nil {
+		return target, fmt.Errorf("service %s/%s has no port matching number %d or name %q",
+			target.namespace, f.target.Service, f.target.Port, f.target.PortName)
+	}
+
+	slices := &discoveryv1.EndpointSliceList{}
+	if err := r.List(ctx, slices, resources.WithLabelSelector(
+		labels.FormatLabels(map[string]string{discoveryv1.LabelServiceName: f.target.Service})),
+	); err != nil {
+		return target, fmt.Errorf("failed to list endpoints of service %s/%s: %w", target.namespace, f.target.Service, err)
+	}
+
+	for _, slice := range slices.Items {
+		port := endpointSlicePort(slice, servicePort.Name)
+		if port == 0 {
+			continue
+		}
+
+		for _, endpoint := range slice.Endpoints {
+			ready := endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready
+			if !ready || endpoint.TargetRef == nil || endpoint.TargetRef.Kind != "Pod" {
+				continue
+			}
+
+			target.pod = endpoint.TargetRef.Name
+			target.port = port
+
+			return target, nil
+		}
+	}
+
+	return target, fmt.Errorf("no ready endpoint found for service %s/%s", target.namespace, f.target.Service)
+}
+
+// newestRunningPod returns the newest running pod matching the label selector.
+func newestRunningPod(ctx context.Context, r *resources.Resources, namespace string, selector map[string]string) (*v1.Pod, error) {
+	pods := &v1.PodList{}
+	if err := r.List(ctx, pods, resources.WithLabelSelector(labels.FormatLabels(selector))); err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
 	}
 
-	ports, err := fw.GetPorts()
+	running := make([]v1.Pod, 0, len(pods.Items))
+
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp == nil && pod.Status.Phase == v1.PodRunning {
+			running = append(running, pod)
+		}
+	}
+
+	if len(running) == 0 {
+		return nil, fmt.Errorf("no running pod found in namespace %s for selector %s", namespace, labels.FormatLabels(selector))
+	}
+
+	sort.Slice(running, func(i, j int) bool {
+		return running[j].CreationTimestamp.Before(&running[i].CreationTimestamp)
+	})
+
+	return &running[0], nil
+}
+
+// containerPortByName returns the number of the named container port of the pod.
+func containerPortByName(pod *v1.Pod, name string) (int, error) {
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			if port.Name == name {
+				return int(port.ContainerPort), nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("pod %s/%s has no container port named %s", pod.Namespace, pod.Name, name)
+}
+
+// endpointSlicePort returns the port number of the named port in the slice, or 0 if it's missing.
+func endpointSlicePort(slice discoveryv1.EndpointSlice, name string) int {
+	for _, port := range slice.Ports {
+		portName := ""
+		if port.Name != nil {
+			portName = *port.Name
+		}
+
+		if portName == name && port.Port != nil {
+			return int(*port.Port)
+		}
+	}
+
+	return 0
+}
+
+func (f *PortForwarder) recordError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.errs = append(f.errs, err)
+}
+
+func forwardError(err error) error {
+	if err == nil {
+		return errors.New("port forward connection closed")
+	}
+
+	return fmt.Errorf("port forward failed: %w", err)
+}
+
+// freeLocalPort asks the kernel for a free local port.
+func freeLocalPort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
-		return ctx, fmt.Errorf("failed to get ports: %w", err)
+		return 0, fmt.Errorf("failed to find free local port: %w", err)
 	}
+	defer listener.Close()
 
-	if len(ports) != 1 {
-		return ctx, fmt.Errorf("failed to get expected ports: %+v", ports)
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// portForwardRegistry holds the port forwards of an environment by name.
+type portForwardRegistry struct {
+	mu       sync.Mutex
+	forwards map[string]*PortForwarder
+}
+
+type portForwardRegistryKey struct{}
+
+// registerPortForward adds the forwarder to the registry in the context, creating the registry if needed.
+func registerPortForward(ctx context.Context, forwarder *PortForwarder) (context.Context, error) {
+	registry, ok := ctx.Value(portForwardRegistryKey{}).(*portForwardRegistry)
+	if !ok {
+		registry = &portForwardRegistry{forwards: map[string]*PortForwarder{}}
+		ctx = context.WithValue(ctx, portForwardRegistryKey{}, registry)
 	}
 
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if _, exists := registry.forwards[forwarder.Name()]; exists {
+		return ctx, fmt.Errorf("port forward %s is already registered", forwarder.Name())
+	}
+
+	registry.forwards[forwarder.Name()] = forwarder
+
 	return ctx, nil
 }
 
-// ForwardPortForAppName port forwards at test setup phase
-func ForwardPortForAppName(name string, port int, stopChannel chan struct{}) env.Func {
+// ForwardPort starts a port forward to the given target and registers it in the context under name. If localPort
+// is 0, a free local port is chosen; use PortForwardAddress to look up the address. Registered port forwards
+// are closed by StopPortForwards.
+func ForwardPort(name string, target PortForwardTarget, localPort int) env.Func {
+	return func(ctx context.Context, config *envconf.Config) (context.Context, error) {
+		if _, err := PortForwardFromContext(ctx, name); err == nil {
+			return ctx, fmt.Errorf("port forward %s is already registered", name)
+		}
+
+		forwarder := NewPortForwarder(name, config, target, localPort)
+		if err := forwarder.Start(ctx); err != nil {
+			return ctx, err
+		}
+
+		ctx, err := registerPortForward(ctx, forwarder)
+		if err != nil {
+			forwarder.Stop()
+
+			return ctx, err
+		}
+
+		fmt.Printf("port forward %s listening on %s\n", name, forwarder.Address())
+
+		return ctx, nil
+	}
+}
+
+// StopPortForward stops the port forward registered under name.
+func StopPortForward(name string) env.Func {
 	return func(ctx context.Context, config *envconf.Config) (context.Context, error) {
-		podName, err := getPodNameForApp(ctx, config, name)
-		if err != nil || podName == "" {
-			return ctx, fmt.Errorf("failed to get pod for the registry: %w", err)
+		forwarder, err := PortForwardFromContext(ctx, name)
+		if err != nil {
+			return ctx, err
 		}
-		return PortForward(port, stopChannel, podName, ctx, config)
+
+		forwarder.Stop()
+
+		return ctx, nil
 	}
 }
 
-// getPodNameForApp returns the name of the pod the registry is running in for port-forwarding requests to.
-func getPodNameForApp(ctx context.Context, config *envconf.Config, name string) (string, error) {
-	r, err := resources.New(config.Client().RESTConfig())
-	if err != nil {
-		return "", fmt.Errorf("failed to create resource client: %w", err)
+// StopPortForwards stops every port forward registered in the context. Add it to the Finish functions of
+// the environment, before the cluster is destroyed.
+func StopPortForwards() env.Func {
+	return func(ctx context.Context, config *envconf.Config) (context.Context, error) {
+		registry, ok := ctx.Value(portForwardRegistryKey{}).(*portForwardRegistry)
+		if !ok {
+			return ctx, nil
+		}
+
+		registry.mu.Lock()
+		defer registry.mu.Unlock()
+
+		for name, forwarder := range registry.forwards {
+			forwarder.Stop()
+
+			for _, err := range forwarder.Errors() {
+				fmt.Printf("port forward %s recorded error: %s\n", name, err)
+			}
+		}
+
+		return ctx, nil
 	}
+}
 
-	if err := v1.AddToScheme(r.GetScheme()); err != nil {
-		return "", fmt.Errorf("failed to add schema to resource client: %w", err)
+// PortForwardFromContext returns the port forward registered under name.
+func PortForwardFromContext(ctx context.Context, name string) (*PortForwarder, error) {
+	registry, ok := ctx.Value(portForwardRegistryKey{}).(*portForwardRegistry)
+	if !ok {
+		return nil, fmt.Errorf("port forward %s not found in context", name)
 	}
 
-	pods := &v1.PodList{}
-	if err := r.List(ctx, pods, resources.WithLabelSelector(
-		labels.FormatLabels(map[string]string{"app": name})),
-	); err != nil {
-		return "", fmt.Errorf("failed to list pods: %w", err)
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	forwarder, ok := registry.forwards[name]
+	if !ok {
+		return nil, fmt.Errorf("port forward %s not found in context", name)
 	}
 
-	if len(pods.Items) != 1 {
-		return "", fmt.Errorf("invalid number of pods found for registry %d", len(pods.Items))
+	return forwarder, nil
+}
+
+// PortForwardAddress returns the local host:port address of the port forward registered under name.
+func PortForwardAddress(ctx context.Context, name string) (string, error) {
+	forwarder, err := PortForwardFromContext(ctx, name)
+	if err != nil {
+		return "", err
+	}
+
+	return forwarder.Address(), nil
+}
+
+// PortForward forwards the given port for the given pod name.
+//
+// Deprecated: use ForwardPort with PortForwardTarget.PodName, which doesn't need a stop channel.
+func PortForward(port int, stopChannel chan struct{}, podName string, ctx context.Context, config *envconf.Config) (context.Context, error) {
+	forwarder := NewPortForwarder(podName, config, PortForwardTarget{PodName: podName, Port: port}, port)
+	if err := forwarder.Start(ctx); err != nil {
+		return ctx, err
 	}
 
-	return pods.Items[0].Name, nil
+	go func() {
+		<-stopChannel
+		forwarder.Stop()
+	}()
+
+	return ctx, nil
 }
 
-// ShutdownPortForward sends a signal to the stop channel.
+// ForwardPortForAppName port forwards at test setup phase. The local port is the same as the pod port.
+// The forward is also registered in the context under the app name, so StopPortForwards closes it too.
+//
+// Deprecated: use ForwardPort with PortForwardTarget.LabelSelector, which doesn't need a stop channel.
+func ForwardPortForAppName(name string, port int, stopChannel chan struct{}) env.Func {
+	return func(ctx context.Context, config *envconf.Config) (context.Context, error) {
+		ctx, err := ForwardPort(name, PortForwardTarget{
+			LabelSelector: map[string]string{"app": name},
+			Port:          port,
+		}, port)(ctx, config)
+		if err != nil {
+			return ctx, err
+		}
+
+		forwarder, err := PortForwardFromContext(ctx, name)
+		if err != nil {
+			return ctx, err
+		}
+
+		go func() {
+			<-stopChannel
+			forwarder.Stop()
+		}()
+
+		return ctx, nil
+	}
+}
+
+// ShutdownPortForward signals the stop channel. It doesn't block if nothing is listening on the channel.
+//
+// Deprecated: use StopPortForwards.
 func ShutdownPortForward(stopChannel chan struct{}) env.Func {
 	return func(ctx context.Context, config *envconf.Config) (context.Context, error) {
-		stopChannel <- struct{}{}
+		select {
+		case stopChannel <- struct{}{}:
+		default:
+		}
 
 		return ctx, nil
 	}
@@ -15,6 +15,7 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/e2e-framework/klient/decoder"
 	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
@@ -39,14 +40,23 @@ var (
 )
 
 // StartGitServer installs a Gitea Git server into the cluster using the deployment configuration files provided
-// under ./gitea folder.
-func StartGitServer(namespace string) env.Func {
+// under ./gitea folder. The deployment can be customized with GitServerOptions.
+func StartGitServer(namespace string, opts ...GitServerOption) env.Func {
 	return func(ctx context.Context, c *envconf.Config) (context.Context, error) {
+		options := newGitServerOptions(opts...)
+
 		r, err := resources.New(c.Client().RESTConfig())
 		if err != nil {
 			return ctx, fmt.Errorf("failed to create rest client: %w", err)
 		}
 
+		// a claim left by a previous run on a reused cluster keeps its data
+		if options.persistence != nil {
+			if err := r.Create(ctx, options.persistentVolumeClaim(namespace)); err != nil && !apierrors.IsAlreadyExists(err) {
+				return ctx, fmt.Errorf("failed to create gitea persistent volume claim: %w", err)
+			}
+		}
+
 		location, err := createLocalizedDeployment(namespace)
 		if err != nil {
 			return ctx, fmt.Errorf("failed to create localized deployment: %w", err)
@@ -58,6 +68,7 @@ func StartGitServer(namespace string) env.Func {
 			ctx, os.DirFS(location), "*",
 			decoder.CreateHandler(r),
 			decoder.MutateNamespace(namespace),
+			decoder.MutateOption(options.mutate),
 		); err != nil {
 			return ctx, fmt.Errorf("failed to apply gitea configuration files: %w", err)
 		}
@@ -80,9 +91,12 @@ func StartGitServer(namespace string) env.Func {
 	}
 }
 
-// RemoveGitServer removes the previously installed Gitea server.
-func RemoveGitServer(namespace string) env.Func {
+// RemoveGitServer removes the previously installed Gitea server. It has to be called with the same
+// GitServerOptions as StartGitServer so a persistent volume claim is removed as well.
+func RemoveGitServer(namespace string, opts ...GitServerOption) env.Func {
 	return func(ctx context.Context, c *envconf.Config) (context.Context, error) {
+		options := newGitServerOptions(opts...)
+
 		r, err := resources.New(c.Client().RESTConfig())
 		if err != nil {
 			return ctx, fmt.Errorf("failed to create rest client: %w", err)
@@ -103,6 +117,12 @@ func RemoveGitServer(namespace string) env.Func {
 			return ctx, fmt.Errorf("failed to apply gitea configuration files: %w", err)
 		}
 
+		if options.persistence != nil {
+			if err := r.Delete(ctx, options.persistentVolumeClaim(namespace)); err != nil {
+				return ctx, fmt.Errorf("failed to delete gitea persistent volume claim: %w", err)
+			}
+		}
+
 		return ctx, nil
 	}
 }
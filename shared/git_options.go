@@ -0,0 +1,190 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package shared
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+)
+
+const (
+	giteaImageRepository = "gitea/gitea"
+	giteaContainerName   = "gitea"
+	giteaDataVolumeName  = "data"
+	giteaDataClaimName   = "gitea-data"
+)
+
+// GitServerOption customizes the Gitea deployment created by StartGitServer.
+type GitServerOption func(*gitServerOptions)
+
+type gitServerPersistence struct {
+	storageClass string
+	size         resource.Quantity
+}
+
+type gitServerOptions struct {
+	image       string
+	resources   *corev1.ResourceRequirements
+	persistence *gitServerPersistence
+	config      map[string]string
+	serviceType corev1.ServiceType
+}
+
+// WithGiteaVersion uses the given version of the gitea/gitea image.
+func WithGiteaVersion(version string) GitServerOption {
+	return func(o *gitServerOptions) {
+		o.image = fmt.Sprintf("%s:%s", giteaImageRepository, version)
+	}
+}
+
+// WithGiteaImage uses the given image reference for the Gitea container, for example from a mirror.
+func WithGiteaImage(image string) GitServerOption {
+	return func(o *gitServerOptions) {
+		o.image = image
+	}
+}
+
+// WithGiteaResources sets the resource requests and limits of the Gitea container.
+func WithGiteaResources(resources corev1.ResourceRequirements) GitServerOption {
+	return func(o *gitServerOptions) {
+		o.resources = &resources
+	}
+}
+
+// WithGiteaPersistence stores the Gitea data in a persistent volume claim of the given size instead of an
+// emptyDir. If storageClass is empty, the default storage class of the cluster is used. The initial Gitea data
+// is only written to an empty volume, so repositories and users survive restarts of the pod. An existing claim,
+// for example in a cluster kept alive from a previous run, is reused.
+func WithGiteaPersistence(storageClass string, size resource.Quantity) GitServerOption {
+	return func(o *gitServerOptions) {
+		o.persistence = &gitServerPersistence{
+			storageClass: storageClass,
+			size:         size,
+		}
+	}
+}
+
+// WithGiteaConfig overrides a setting of the Gitea app.ini, for example
+// WithGiteaConfig("webhook", "ALLOWED_HOST_LIST", "*") or WithGiteaConfig("server", "LFS_START_SERVER", "true").
+func WithGiteaConfig(section, key, value string) GitServerOption {
+	return func(o *gitServerOptions) {
+		o.config[giteaConfigEnvName(section, key)] = value
+	}
+}
+
+// WithGiteaServiceType sets the type of the Gitea service.
+func WithGiteaServiceType(serviceType corev1.ServiceType) GitServerOption {
+	return func(o *gitServerOptions) {
+		o.serviceType = serviceType
+	}
+}
+
+func newGitServerOptions(opts ...GitServerOption) *gitServerOptions {
+	options := &gitServerOptions{
+		config: map[string]string{},
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return options
+}
+
+// mutate applies the options to the objects of the Gitea deployment.
+func (o *gitServerOptions) mutate(obj k8s.Object) error {
+	switch obj := obj.(type) {
+	case *appsv1.Deployment:
+		o.mutateDeployment(obj)
+	case *corev1.Service:
+		if o.serviceType != "" {
+			obj.Spec.Type = o.serviceType
+		}
+	}
+
+	return nil
+}
+
+func (o *gitServerOptions) mutateDeployment(deployment *appsv1.Deployment) {
+	spec := &deployment.Spec.Template.Spec
+
+	for i := range spec.Containers {
+		container := &spec.Containers[i]
+		if container.Name != giteaContainerName {
+			continue
+		}
+
+		if o.image != "" {
+			container.Image = o.image
+		}
+
+		if o.resources != nil {
+			container.Resources = *o.resources
+		}
+
+		// the gitea image translates GITEA__section__KEY variables into app.ini settings on startup
+		keys := make([]string, 0, len(o.config))
+		for key := range o.config {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			container.Env = append(container.Env, corev1.EnvVar{Name: key, Value: o.config[key]})
+		}
+	}
+
+	if o.persistence == nil {
+		return
+	}
+
+	for i := range spec.Volumes {
+		if spec.Volumes[i].Name == giteaDataVolumeName {
+			spec.Volumes[i].VolumeSource = corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: giteaDataClaimName,
+				},
+			}
+		}
+	}
+}
+
+func (o *gitServerOptions) persistentVolumeClaim(namespace string) *corev1.PersistentVolumeClaim {
+	claim := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      giteaDataClaimName,
+			Namespace: namespace,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: o.persistence.size,
+				},
+			},
+		},
+	}
+
+	if o.persistence.storageClass != "" {
+		claim.Spec.StorageClassName = &o.persistence.storageClass
+	}
+
+	return claim
+}
+
+// giteaConfigEnvName returns the environment variable name the gitea image maps to the given app.ini setting.
+func giteaConfigEnvName(section, key string) string {
+	replacer := strings.NewReplacer(".", "_0X2E_", "-", "_0X2D_")
+
+	return fmt.Sprintf("GITEA__%s__%s", replacer.Replace(section), strings.ToUpper(replacer.Replace(key)))
+}
@@ -0,0 +1,151 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package shared
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/envfuncs"
+)
+
+// ControllerImage is a locally built controller image that replaces the image of a Deployment.
+type ControllerImage struct {
+	// Deployment of the controller.
+	Deployment string
+	// Namespace of the Deployment. Defaults to DefaultNamespace.
+	Namespace string
+	// Container whose image is replaced. Defaults to the first container of the Deployment.
+	Container string
+	// Image is the reference of the image, for example ocm-controller:dev. It must be available in the local
+	// docker daemon, unless Archive is set.
+	Image string
+	// Archive is an image tarball created with docker save. It must contain Image.
+	Archive string
+}
+
+// SideLoadControllerImages loads the images into the kind or k3d cluster and patches the controller
+// Deployments to use them with imagePullPolicy IfNotPresent, so no registry is needed. It waits until
+// the Deployments are rolled out.
+func SideLoadControllerImages(cluster ClusterOptions, images ...ControllerImage) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		if cluster.Provider == ClusterProviderExisting {
+			return ctx, errors.New("images can't be side-loaded into an existing cluster")
+		}
+
+		for _, image := range images {
+			if image.Image == "" {
+				return ctx, errors.New("image reference of side-loaded controller image is required")
+			}
+
+			if image.Namespace == "" {
+				image.Namespace = DefaultNamespace
+			}
+
+			if image.Archive != "" {
+				if _, err := envfuncs.LoadImageArchiveToCluster(cluster.Name, image.Archive)(ctx, cfg); err != nil {
+					return ctx, fmt.Errorf("failed to load image archive %s into cluster %s: %w", image.Archive, cluster.Name, err)
+				}
+			} else if err := sideLoadImage(ctx, cfg, cluster, image.Image, false); err != nil {
+				return ctx, err
+			}
+
+			if err := setDeploymentImage(ctx, cfg, image); err != nil {
+				return ctx, err
+			}
+		}
+
+		return ctx, nil
+	}
+}
+
+// setDeploymentImage patches the container image of a Deployment and waits for the rollout.
+func setDeploymentImage(ctx context.Context, cfg *envconf.Config, image ControllerImage) error {
+	deployment := &appsv1.Deployment{}
+	if err := cfg.Client().Resources().Get(ctx, image.Deployment, image.Namespace, deployment); err != nil {
+		return fmt.Errorf("failed to get deployment %s/%s: %w", image.Namespace, image.Deployment, err)
+	}
+
+	containers := deployment.Spec.Template.Spec.Containers
+	index := -1
+
+	for i := range containers {
+		if image.Container == "" || containers[i].Name == image.Container {
+			index = i
+
+			break
+		}
+	}
+
+	if index < 0 {
+		return fmt.Errorf("container %q not found in deployment %s/%s", image.Container, image.Namespace, image.Deployment)
+	}
+
+	containers[index].Image = image.Image
+	containers[index].ImagePullPolicy = corev1.PullIfNotPresent
+
+	if err := cfg.Client().Resources().Update(ctx, deployment); err != nil {
+		return fmt.Errorf("failed to update deployment %s/%s: %w", image.Namespace, image.Deployment, err)
+	}
+
+	if err := wait.For(
+		conditions.New(cfg.Client().Resources()).ResourceMatch(deployment, deploymentRolledOut),
+		wait.WithTimeout(timeout),
+		wait.WithContext(ctx),
+	); err != nil {
+		return fmt.Errorf("deployment %s/%s didn't roll out image %s: %w", image.Namespace, image.Deployment, image.Image, err)
+	}
+
+	fmt.Printf("deployment %s/%s uses side-loaded image %s\n", image.Namespace, image.Deployment, image.Image)
+
+	return nil
+}
+
+func deploymentRolledOut(object k8s.Object) bool {
+	deployment, ok := object.(*appsv1.Deployment)
+	if !ok {
+		return false
+	}
+
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+
+	return deployment.Status.ObservedGeneration >= deployment.Generation &&
+		deployment.Status.UpdatedReplicas == replicas &&
+		deployment.Status.AvailableReplicas == replicas &&
+		deployment.Status.Replicas == replicas
+}
+
+// sideLoadImage loads an image from the local docker daemon into the kind or k3d cluster created by CreateCluster.
+// If pull is set, the image is pulled first.
+func sideLoadImage(ctx context.Context, cfg *envconf.Config, cluster ClusterOptions, image string, pull bool) error {
+	if cluster.Provider == ClusterProviderExisting {
+		return fmt.Errorf("can't load image %s into an existing cluster", image)
+	}
+
+	if pull {
+		output, err := exec.CommandContext(ctx, "docker", "pull", image).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("failed to pull image %s: %w\n%s", image, err, string(output))
+		}
+	}
+
+	if _, err := envfuncs.LoadImageToCluster(cluster.Name, image)(ctx, cfg); err != nil {
+		return fmt.Errorf("failed to load image %s into cluster %s: %w", image, cluster.Name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package shared
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	lfsMediaType      = "application/vnd.git-lfs+json"
+	lfsPointerVersion = "https://git-lfs.github.com/spec/v1"
+)
+
+// LFSPointer returns the content of the Git LFS pointer file for the given data.
+func LFSPointer(data []byte) string {
+	sum := sha256.Sum256(data)
+
+	return fmt.Sprintf("version %s\noid sha256:%s\nsize %d\n", lfsPointerVersion, hex.EncodeToString(sum[:]), len(data))
+}
+
+// IsLFSPointer returns whether the given content is a Git LFS pointer file.
+func IsLFSPointer(content []byte) bool {
+	return strings.HasPrefix(string(content), "version "+lfsPointerVersion+"\n")
+}
+
+type lfsBatchObject struct {
+	OID     string `json:"oid"`
+	Size    int    `json:"size"`
+	Actions map[string]struct {
+		Href   string            `json:"href"`
+		Header map[string]string `json:"header"`
+	} `json:"actions,omitempty"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+type lfsBatchRequest struct {
+	Operation string           `json:"operation"`
+	Transfers []string         `json:"transfers"`
+	Objects   []lfsBatchObject `json:"objects"`
+}
+
+type lfsBatchResponse struct {
+	Objects []lfsBatchObject `json:"objects"`
+}
+
+// UploadLFSObject stores data in the LFS store of a repository using the Git LFS batch API and returns the
+// pointer file content that has to be committed in place of the data. The LFS server is enabled in the app.ini of
+// the test Gitea.
+func UploadLFSObject(owner, repo string, data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	object := lfsBatchObject{OID: hex.EncodeToString(sum[:]), Size: len(data)}
+
+	body, err := json.Marshal(lfsBatchRequest{
+		Operation: "upload",
+		Transfers: []string{"basic"},
+		Objects:   []lfsBatchObject{object},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal lfs batch request: %w", err)
+	}
+
+	batchURL := fmt.Sprintf("%s/%s/%s.git/info/lfs/objects/batch", BaseURL, owner, repo)
+
+	req, err := http.NewRequest(http.MethodPost, batchURL, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create lfs batch request: %w", err)
+	}
+
+	req.Header.Set("Accept", lfsMediaType)
+	req.Header.Set("Content-Type", lfsMediaType)
+	req.SetBasicAuth(Owner, TestUserToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send lfs batch request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		content, _ := io.ReadAll(resp.Body)
+
+		return "", fmt.Errorf("lfs batch request for %s/%s failed with status %d: %s", owner, repo, resp.StatusCode, string(content))
+	}
+
+	batch := lfsBatchResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
+		return "", fmt.Errorf("failed to decode lfs batch response: %w", err)
+	}
+
+	if len(batch.Objects) != 1 {
+		return "", fmt.Errorf("unexpected number of objects in lfs batch response: %d", len(batch.Objects))
+	}
+
+	if batch.Objects[0].Error != nil {
+		return "", fmt.Errorf("lfs batch request for %s failed: %s", object.OID, batch.Objects[0].Error.Message)
+	}
+
+	// no upload action means the server already has the object
+	if upload, ok := batch.Objects[0].Actions["upload"]; ok {
+		href, err := forwardedURL(upload.Href)
+		if err != nil {
+			return "", err
+		}
+
+		if err := putLFSObject(href, upload.Header, data); err != nil {
+			return "", err
+		}
+	}
+
+	return LFSPointer(data), nil
+}
+
+// forwardedURL points a URL returned by Gitea, which is based on its ROOT_URL, to BaseURL, where Gitea is
+// reachable from the tests.
+func forwardedURL(href string) (string, error) {
+	base, err := url.Parse(BaseURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse gitea base url %s: %w", BaseURL, err)
+	}
+
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse lfs upload url %s: %w", href, err)
+	}
+
+	u.Scheme = base.Scheme
+	u.Host = base.Host
+
+	return u.String(), nil
+}
+
+func putLFSObject(href string, header map[string]string, data []byte) error {
+	req, err := http.NewRequest(http.MethodPut, href, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create lfs upload request: %w", err)
+	}
+
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload lfs object: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		content, _ := io.ReadAll(resp.Body)
+
+		return fmt.Errorf("lfs upload failed with status %d: %s", resp.StatusCode, string(content))
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package shared
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForwardedURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		href     string
+		expected string
+	}{
+		{
+			name:     "root url of gitea",
+			href:     "http://localhost:3000/e2e-tester/repo.git/info/lfs/objects/abc/5",
+			expected: BaseURL + "/e2e-tester/repo.git/info/lfs/objects/abc/5",
+		},
+		{
+			name:     "query is kept",
+			href:     "http://localhost:3000/e2e-tester/repo.git/info/lfs/objects/abc/5?token=x",
+			expected: BaseURL + "/e2e-tester/repo.git/info/lfs/objects/abc/5?token=x",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := forwardedURL(tt.href)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
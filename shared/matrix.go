@@ -0,0 +1,259 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package shared
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+)
+
+// VersionMatrix lists the versions a suite is run against. The features run once for every combination of
+// a Flux version and a version of each controller.
+type VersionMatrix struct {
+	// Flux versions installed with InstallFlux. If empty, Flux isn't installed by the matrix.
+	Flux []string
+	// Controllers maps a controller name, such as ocm-controller, to its versions.
+	Controllers map[string][]string
+	// Release returns the release to install for a controller version. It can be used to install from a
+	// chart or to side-load images. By default, the release manifest is installed.
+	Release func(name, version string) ControllerRelease
+}
+
+// VersionCombination is one entry of a VersionMatrix.
+type VersionCombination struct {
+	Flux        string
+	Controllers map[string]string
+}
+
+// String returns a name for the combination that can be used as a test name.
+func (c VersionCombination) String() string {
+	var parts []string
+
+	if c.Flux != "" {
+		parts = append(parts, "flux-"+c.Flux)
+	}
+
+	for _, name := range sortedKeys(c.Controllers) {
+		parts = append(parts, name+"-"+c.Controllers[name])
+	}
+
+	return strings.Join(parts, "_")
+}
+
+// MatrixResult is the outcome of running the features against a VersionCombination.
+type MatrixResult struct {
+	Combination VersionCombination
+	Passed      bool
+	Duration    time.Duration
+}
+
+// MatrixResults is the compatibility table produced by RunVersionMatrix.
+type MatrixResults []MatrixResult
+
+// Combinations returns every combination of the matrix in a stable order. Flux versions come first, then the
+// controllers by name, and the versions of each in the order they are listed. Duplicate versions are ignored.
+func (m VersionMatrix) Combinations() []VersionCombination {
+	fluxVersions := uniqueVersions(m.Flux)
+	if len(fluxVersions) == 0 {
+		fluxVersions = []string{""}
+	}
+
+	var combinations []VersionCombination
+
+	for _, flux := range fluxVersions {
+		partial := []map[string]string{{}}
+
+		for _, name := range sortedKeys(m.Controllers) {
+			var next []map[string]string
+
+			for _, combination := range partial {
+				for _, version := range uniqueVersions(m.Controllers[name]) {
+					controllers := map[string]string{name: version}
+					for k, v := range combination {
+						controllers[k] = v
+					}
+
+					next = append(next, controllers)
+				}
+			}
+
+			partial = next
+		}
+
+		for _, controllers := range partial {
+			combinations = append(combinations, VersionCombination{Flux: flux, Controllers: controllers})
+		}
+	}
+
+	return combinations
+}
+
+// RunVersionMatrix runs the features once per combination of the matrix in the cluster of testEnv. Before
+// each run, Flux and the controllers are installed at the versions of the combination, and afterwards they
+// are uninstalled, including their CRDs, so the next combination starts clean. Every combination is a subtest.
+// If a combination can't be uninstalled, the remaining combinations are skipped, because they wouldn't run in a
+// clean cluster. The compatibility table is logged and returned.
+func RunVersionMatrix(
+	t *testing.T, testEnv env.Environment, cluster ClusterOptions, matrix VersionMatrix, feats ...features.Feature,
+) MatrixResults {
+	t.Helper()
+
+	var results MatrixResults
+
+	clean := true
+
+	for _, combination := range matrix.Combinations() {
+		if !clean {
+			t.Logf("skipping %s, the previous combination wasn't uninstalled", combination)
+
+			continue
+		}
+
+		releases := matrix.releases(combination)
+		start := time.Now()
+
+		passed := t.Run(combination.String(), func(t *testing.T) {
+			install := features.New("install " + combination.String())
+			uninstall := features.New("uninstall " + combination.String())
+
+			if combination.Flux != "" {
+				install = install.Setup(envStep(InstallFlux(combination.Flux)))
+			}
+
+			install = install.Setup(envStep(InstallControllerReleases(cluster, releases...)))
+			uninstall = uninstall.Teardown(envStep(UninstallControllerReleases(releases...)))
+
+			if combination.Flux != "" {
+				uninstall = uninstall.Teardown(envStep(UninstallFlux(combination.Flux, WithFluxCRDDeletion())))
+			}
+
+			testEnv.Test(t, install.Feature())
+
+			// features fail in their own subtests, so the uninstall runs in any case
+			if !t.Failed() {
+				testEnv.Test(t, feats...)
+			}
+
+			clean = t.Run("uninstall", func(t *testing.T) {
+				testEnv.Test(t, uninstall.Feature())
+			})
+		})
+
+		results = append(results, MatrixResult{
+			Combination: combination,
+			Passed:      passed,
+			Duration:    time.Since(start),
+		})
+	}
+
+	t.Logf("compatibility matrix:\n%s", results.Table())
+
+	return results
+}
+
+// Table returns the results as a Markdown table with a column per component.
+func (r MatrixResults) Table() string {
+	if len(r) == 0 {
+		return ""
+	}
+
+	columns := sortedKeys(r[0].Combination.Controllers)
+	if r[0].Combination.Flux != "" {
+		columns = append([]string{"flux"}, columns...)
+	}
+
+	columns = append(columns, "result", "duration")
+
+	var b strings.Builder
+
+	b.WriteString("| " + strings.Join(columns, " | ") + " |\n")
+	b.WriteString(strings.Repeat("|---", len(columns)) + "|\n")
+
+	for _, result := range r {
+		var row []string
+
+		if result.Combination.Flux != "" {
+			row = append(row, result.Combination.Flux)
+		}
+
+		for _, name := range sortedKeys(result.Combination.Controllers) {
+			row = append(row, result.Combination.Controllers[name])
+		}
+
+		status := "fail"
+		if result.Passed {
+			status = "pass"
+		}
+
+		row = append(row, status, result.Duration.Round(time.Second).String())
+
+		b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+	}
+
+	return b.String()
+}
+
+func (m VersionMatrix) releases(combination VersionCombination) []ControllerRelease {
+	var releases []ControllerRelease
+
+	for _, name := range sortedKeys(combination.Controllers) {
+		version := combination.Controllers[name]
+		if m.Release != nil {
+			releases = append(releases, m.Release(name, version))
+		} else {
+			releases = append(releases, ControllerRelease{Name: name, Version: version})
+		}
+	}
+
+	return releases
+}
+
+// envStep runs an env.Func as a feature step, failing the test if it returns an error.
+func envStep(fn env.Func) features.Func {
+	return func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		t.Helper()
+
+		ctx, err := fn(ctx, cfg)
+		if err != nil {
+			t.Fatal(fmt.Errorf("step failed: %w", err))
+		}
+
+		return ctx
+	}
+}
+
+// uniqueVersions returns versions without duplicates, keeping the first occurrence.
+func uniqueVersions(versions []string) []string {
+	seen := map[string]bool{}
+	unique := make([]string, 0, len(versions))
+
+	for _, version := range versions {
+		if !seen[version] {
+			seen[version] = true
+			unique = append(unique, version)
+		}
+	}
+
+	return unique
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package shared
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionMatrixCombinations(t *testing.T) {
+	tests := []struct {
+		name     string
+		matrix   VersionMatrix
+		expected []string
+	}{
+		{
+			name: "flux first, then controllers by name",
+			matrix: VersionMatrix{
+				Flux: []string{"v2.3.0", "v2.2.3"},
+				Controllers: map[string][]string{
+					"ocm-controller": {"v0.24.0", "v0.23.0"},
+					"git-controller": {"v0.12.0"},
+				},
+			},
+			expected: []string{
+				"flux-v2.3.0_git-controller-v0.12.0_ocm-controller-v0.24.0",
+				"flux-v2.3.0_git-controller-v0.12.0_ocm-controller-v0.23.0",
+				"flux-v2.2.3_git-controller-v0.12.0_ocm-controller-v0.24.0",
+				"flux-v2.2.3_git-controller-v0.12.0_ocm-controller-v0.23.0",
+			},
+		},
+		{
+			name: "duplicate versions",
+			matrix: VersionMatrix{
+				Flux:        []string{"v2.3.0", "v2.3.0"},
+				Controllers: map[string][]string{"ocm-controller": {"v0.24.0", "v0.23.0", "v0.24.0"}},
+			},
+			expected: []string{
+				"flux-v2.3.0_ocm-controller-v0.24.0",
+				"flux-v2.3.0_ocm-controller-v0.23.0",
+			},
+		},
+		{
+			name: "without flux",
+			matrix: VersionMatrix{
+				Controllers: map[string][]string{"ocm-controller": {"v0.24.0"}},
+			},
+			expected: []string{"ocm-controller-v0.24.0"},
+		},
+		{
+			name:     "without controllers",
+			matrix:   VersionMatrix{Flux: []string{"v2.3.0"}},
+			expected: []string{"flux-v2.3.0"},
+		},
+		{
+			name: "controller without versions",
+			matrix: VersionMatrix{
+				Flux:        []string{"v2.3.0"},
+				Controllers: map[string][]string{"ocm-controller": nil},
+			},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var names []string
+			for _, combination := range tt.matrix.Combinations() {
+				names = append(names, combination.String())
+			}
+
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+}
+
+func TestMatrixResultsTable(t *testing.T) {
+	tests := []struct {
+		name     string
+		results  MatrixResults
+		expected string
+	}{
+		{
+			name:     "empty",
+			expected: "",
+		},
+		{
+			name: "flux and controllers",
+			results: MatrixResults{
+				{
+					Combination: VersionCombination{
+						Flux:        "v2.3.0",
+						Controllers: map[string]string{"ocm-controller": "v0.24.0", "git-controller": "v0.12.0"},
+					},
+					Passed:   true,
+					Duration: 90*time.Second + 400*time.Millisecond,
+				},
+				{
+					Combination: VersionCombination{
+						Flux:        "v2.2.3",
+						Controllers: map[string]string{"ocm-controller": "v0.23.0", "git-controller": "v0.12.0"},
+					},
+					Duration: 2 * time.Minute,
+				},
+			},
+			expected: "| flux | git-controller | ocm-controller | result | duration |\n" +
+				"|---|---|---|---|---|\n" +
+				"| v2.3.0 | v0.12.0 | v0.24.0 | pass | 1m30s |\n" +
+				"| v2.2.3 | v0.12.0 | v0.23.0 | fail | 2m0s |\n",
+		},
+		{
+			name: "without flux",
+			results: MatrixResults{
+				{
+					Combination: VersionCombination{Controllers: map[string]string{"ocm-controller": "v0.24.0"}},
+					Passed:      true,
+					Duration:    time.Second,
+				},
+			},
+			expected: "| ocm-controller | result | duration |\n" +
+				"|---|---|---|\n" +
+				"| v0.24.0 | pass | 1s |\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.results.Table())
+		})
+	}
+}
@@ -0,0 +1,33 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package shared
+
+import "context"
+
+type testNamespaceKey struct{}
+
+// WithTestNamespace returns a context that carries the namespace of the running feature.
+func WithTestNamespace(ctx context.Context, namespace string) context.Context {
+	return context.WithValue(ctx, testNamespaceKey{}, namespace)
+}
+
+// TestNamespaceFromContext returns the namespace of the running feature set by setup.CreateTestNamespace.
+func TestNamespaceFromContext(ctx context.Context) (string, bool) {
+	namespace, ok := ctx.Value(testNamespaceKey{}).(string)
+
+	return namespace, ok && namespace != ""
+}
+
+// NamespaceOrDefault returns namespace if it's set. Otherwise, it returns the namespace of the running feature
+// from the context. It returns an empty string if neither is set.
+func NamespaceOrDefault(ctx context.Context, namespace string) string {
+	if namespace != "" {
+		return namespace
+	}
+
+	namespace, _ = TestNamespaceFromContext(ctx)
+
+	return namespace
+}
@@ -0,0 +1,58 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package shared
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// FluxSystemLock is the name of the lock for objects in the flux-system namespace that are shared by features,
+// such as the Flux controllers and their configuration.
+const FluxSystemLock = "flux-system"
+
+var (
+	nameCounter atomic.Uint64
+
+	locksMu sync.Mutex
+	locks   = map[string]*sync.Mutex{}
+)
+
+// UniqueName returns a name based on prefix that no other call returns, in this or another test run. Features
+// that run in parallel use it for Gitea repositories, component names and other objects in shared places.
+func UniqueName(prefix string) (string, error) {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("failed to read random bytes: %w", err)
+	}
+
+	return fmt.Sprintf("%s-%d%s", prefix, nameCounter.Add(1), hex.EncodeToString(suffix)), nil
+}
+
+// LockResource blocks until no other feature holds the named shared resource and then locks it.
+func LockResource(name string) {
+	lockFor(name).Lock()
+}
+
+// UnlockResource releases the named shared resource locked by LockResource.
+func UnlockResource(name string) {
+	lockFor(name).Unlock()
+}
+
+func lockFor(name string) *sync.Mutex {
+	locksMu.Lock()
+	defer locksMu.Unlock()
+
+	lock, ok := locks[name]
+	if !ok {
+		lock = &sync.Mutex{}
+		locks[name] = lock
+	}
+
+	return lock
+}
@@ -0,0 +1,400 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package shared
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	runclient "github.com/fluxcd/pkg/runtime/client"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"sigs.k8s.io/e2e-framework/klient/conf"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/third_party/helm"
+
+	"github.com/open-component-model/ocm-e2e-framework/internal/utils"
+)
+
+const releaseManifestURL = "https://github.com/open-component-model/%s/releases/download/%s/install.yaml"
+
+// ControllerRelease describes a released controller, such as ocm-controller, git-controller,
+// replication-controller, mpas-product-controller or mpas-project-controller, installed at a pinned version.
+type ControllerRelease struct {
+	// Name of the controller. It's the name of its GitHub repository under open-component-model.
+	Name string
+	// Version is the release tag, for example v0.13.1.
+	Version string
+	// Namespace the chart is installed into. Defaults to DefaultNamespace. A release manifest can't be moved to
+	// another namespace, so for a manifest it has to match the namespace of the manifest's Deployments.
+	Namespace string
+	// ManifestURL overrides the install.yaml of the GitHub release.
+	ManifestURL string
+	// Chart installs the controller from a Helm chart instead of the release manifest, for example
+	// oci://ghcr.io/open-component-model/helm/ocm-controller. Version is used as the chart version.
+	Chart string
+	// HelmArgs are additional arguments for helm install, such as --set flags.
+	HelmArgs []string
+	// SideLoad pulls the images on the host and loads them into the cluster, instead of letting the cluster
+	// pull them. The images of a release manifest are found automatically, the images of a chart have to be
+	// listed in Images.
+	SideLoad bool
+	// Images are additional images to side-load.
+	Images []string
+}
+
+// InstallControllerReleases installs released controllers without their source or Tilt. The cluster is
+// needed to side-load images. The function waits until every Deployment of a release is available.
+func InstallControllerReleases(cluster ClusterOptions, releases ...ControllerRelease) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		for _, release := range releases {
+			if release.Namespace == "" {
+				release.Namespace = DefaultNamespace
+			}
+
+			var err error
+			if release.Chart != "" {
+				err = installChart(ctx, cfg, cluster, release)
+			} else {
+				err = installReleaseManifest(ctx, cfg, cluster, release)
+			}
+
+			if err != nil {
+				return ctx, fmt.Errorf("failed to install %s %s: %w", release.Name, release.Version, err)
+			}
+		}
+
+		return ctx, nil
+	}
+}
+
+func installReleaseManifest(ctx context.Context, cfg *envconf.Config, cluster ClusterOptions, release ControllerRelease) error {
+	tmpDir, err := os.MkdirTemp("", "controller-release")
+	if err != nil {
+		return fmt.Errorf("failed to create temp folder: %w", err)
+	}
+
+	defer os.RemoveAll(tmpDir)
+
+	url := release.ManifestURL
+	if url == "" {
+		url = fmt.Sprintf(releaseManifestURL, release.Name, release.Version)
+	}
+
+	manifestPath := filepath.Join(tmpDir, "install.yaml")
+	if err := download(ctx, url, manifestPath); err != nil {
+		return err
+	}
+
+	objects, err := readObjectsFromFile(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	var deployments []string
+
+	images := release.Images
+
+	for _, obj := range objects {
+		if obj.GetKind() != "Deployment" {
+			continue
+		}
+
+		// the manifest is applied as is, so the Deployments would never show up in another namespace
+		if obj.GetNamespace() != "" && obj.GetNamespace() != release.Namespace {
+			return fmt.Errorf("manifest installs deployment %s into namespace %s, not %s; Namespace only applies to charts",
+				obj.GetName(), obj.GetNamespace(), release.Namespace)
+		}
+
+		deployments = append(deployments, obj.GetName())
+		images = append(images, containerImages(obj)...)
+	}
+
+	if release.SideLoad {
+		for _, image := range images {
+			if err := sideLoadImage(ctx, cfg, cluster, image, true); err != nil {
+				return err
+			}
+		}
+	}
+
+	kubeConfig := cfg.KubeconfigFile()
+	if kubeConfig == "" {
+		kubeConfig = conf.ResolveKubeConfigFile()
+	}
+
+	kfg := genericclioptions.ConfigFlags{KubeConfig: &kubeConfig}
+
+	if cfg.KubeContext() != "" {
+		kubeContext := cfg.KubeContext()
+		kfg.Context = &kubeContext
+	}
+
+	runOpts := &runclient.Options{
+		QPS:   maximumQueriesPerSecond,
+		Burst: burst,
+	}
+
+	if _, err := utils.Apply(ctx, &kfg, runOpts, tmpDir, manifestPath); err != nil {
+		return fmt.Errorf("apply failed: %w", err)
+	}
+
+	return waitForDeployments(ctx, cfg, release.Namespace, deployments)
+}
+
+func installChart(ctx context.Context, cfg *envconf.Config, cluster ClusterOptions, release ControllerRelease) error {
+	if release.SideLoad {
+		for _, image := range release.Images {
+			if err := sideLoadImage(ctx, cfg, cluster, image, true); err != nil {
+				return err
+			}
+		}
+	}
+
+	kubeConfig := cfg.KubeconfigFile()
+	if kubeConfig == "" {
+		kubeConfig = conf.ResolveKubeConfigFile()
+	}
+
+	args := append([]string{"--create-namespace"}, release.HelmArgs...)
+	if cfg.KubeContext() != "" {
+		args = append(args, "--kube-context", cfg.KubeContext())
+	}
+
+	if err := helm.New(kubeConfig).RunInstall(
+		helm.WithName(release.Name),
+		helm.WithNamespace(release.Namespace),
+		helm.WithChart(release.Chart),
+		helm.WithVersion(release.Version),
+		helm.WithArgs(args...),
+		helm.WithWait(),
+		helm.WithTimeout(timeout.String()),
+		helm.WithContext(ctx),
+	); err != nil {
+		return fmt.Errorf("helm install failed: %w", err)
+	}
+
+	return nil
+}
+
+func download(ctx context.Context, url, path string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request for %s: %w", url, err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: status %d", url, resp.StatusCode)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// containerImages returns the images of the containers and init containers of a Deployment.
+func containerImages(deployment *unstructured.Unstructured) []string {
+	var images []string
+
+	for _, field := range []string{"initContainers", "containers"} {
+		containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", field)
+		for _, c := range containers {
+			container, ok := c.(map[string]any)
+			if !ok {
+				continue
+			}
+
+			if image, ok := container["image"].(string); ok {
+				images = append(images, image)
+			}
+		}
+	}
+
+	return images
+}
+
+// waitForDeployments waits until the named Deployments in namespace are available.
+func waitForDeployments(ctx context.Context, cfg *envconf.Config, namespace string, names []string) error {
+	for _, name := range names {
+		deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+
+		if err := wait.For(
+			conditions.New(cfg.Client().Resources()).DeploymentConditionMatch(deployment, appsv1.DeploymentAvailable, corev1.ConditionTrue),
+			wait.WithTimeout(timeout),
+			wait.WithContext(ctx),
+		); err != nil {
+			return fmt.Errorf("deployment %s/%s didn't become available: %w", namespace, name, err)
+		}
+	}
+
+	return nil
+}
+
+// UninstallControllerReleases removes controllers installed by InstallControllerReleases, including their CRDs
+// and custom resources, so another version can be installed. Namespaces are left alone. The CRDs of a chart are
+// the ones listed by helm show crds, which helm uninstall keeps.
+func UninstallControllerReleases(releases ...ControllerRelease) env.Func {
+	return func(ctx context.Context, cfg *envconf.Config) (context.Context, error) {
+		for _, release := range releases {
+			if release.Namespace == "" {
+				release.Namespace = DefaultNamespace
+			}
+
+			var err error
+			if release.Chart != "" {
+				err = uninstallChart(ctx, cfg, release)
+			} else {
+				err = uninstallReleaseManifest(ctx, cfg, release)
+			}
+
+			if err != nil {
+				return ctx, fmt.Errorf("failed to uninstall %s %s: %w", release.Name, release.Version, err)
+			}
+		}
+
+		return ctx, nil
+	}
+}
+
+func uninstallReleaseManifest(ctx context.Context, cfg *envconf.Config, release ControllerRelease) error {
+	tmpDir, err := os.MkdirTemp("", "controller-release")
+	if err != nil {
+		return fmt.Errorf("failed to create temp folder: %w", err)
+	}
+
+	defer os.RemoveAll(tmpDir)
+
+	url := release.ManifestURL
+	if url == "" {
+		url = fmt.Sprintf(releaseManifestURL, release.Name, release.Version)
+	}
+
+	manifestPath := filepath.Join(tmpDir, "install.yaml")
+	if err := download(ctx, url, manifestPath); err != nil {
+		return err
+	}
+
+	objects, err := readObjectsFromFile(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	var crds []*unstructured.Unstructured
+
+	// custom resources go first, while the controller can still remove their finalizers
+	for _, obj := range objects {
+		if obj.GetKind() == "CustomResourceDefinition" {
+			crds = append(crds, obj)
+
+			if err := deleteCustomResources(ctx, cfg, obj, ""); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i := len(objects) - 1; i >= 0; i-- {
+		if kind := objects[i].GetKind(); kind == "Namespace" || kind == "CustomResourceDefinition" {
+			continue
+		}
+
+		if err := deleteIfExists(ctx, cfg, objects[i]); err != nil {
+			return err
+		}
+	}
+
+	return deleteCRDs(ctx, cfg, crds)
+}
+
+func uninstallChart(ctx context.Context, cfg *envconf.Config, release ControllerRelease) error {
+	crds, err := chartCRDs(ctx, release)
+	if err != nil {
+		return err
+	}
+
+	// custom resources go first, while the controller can still remove their finalizers
+	for _, crd := range crds {
+		if err := deleteCustomResources(ctx, cfg, crd, ""); err != nil {
+			return err
+		}
+	}
+
+	kubeConfig := cfg.KubeconfigFile()
+	if kubeConfig == "" {
+		kubeConfig = conf.ResolveKubeConfigFile()
+	}
+
+	var args []string
+	if cfg.KubeContext() != "" {
+		args = append(args, "--kube-context", cfg.KubeContext())
+	}
+
+	if err := helm.New(kubeConfig).RunUninstall(
+		helm.WithReleaseName(release.Name),
+		helm.WithNamespace(release.Namespace),
+		helm.WithArgs(args...),
+		helm.WithWait(),
+		helm.WithTimeout(timeout.String()),
+		helm.WithContext(ctx),
+	); err != nil {
+		return fmt.Errorf("helm uninstall failed: %w", err)
+	}
+
+	return deleteCRDs(ctx, cfg, crds)
+}
+
+// chartCRDs returns the CRDs in the crds directory of a chart. Helm installs them, but never deletes them.
+func chartCRDs(ctx context.Context, release ControllerRelease) ([]*unstructured.Unstructured, error) {
+	output, err := exec.CommandContext(ctx, "helm", "show", "crds", release.Chart, "--version", release.Version).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get crds of chart %s %s: %w", release.Chart, release.Version, err)
+	}
+
+	crds, err := decodeObjects(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode crds of chart %s %s: %w", release.Chart, release.Version, err)
+	}
+
+	return crds, nil
+}
+
+// deleteCRDs deletes the CRDs and waits until they are gone.
+func deleteCRDs(ctx context.Context, cfg *envconf.Config, crds []*unstructured.Unstructured) error {
+	for _, crd := range crds {
+		if err := deleteIfExists(ctx, cfg, crd); err != nil {
+			return err
+		}
+
+		if err := wait.For(conditions.New(cfg.Client().Resources()).ResourceDeleted(crd), wait.WithTimeout(timeout)); err != nil {
+			return fmt.Errorf("crd %s wasn't deleted: %w", crd.GetName(), err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package shared
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// OCMConfigKey is the key of the OCM configuration in secrets referenced by the OCM controllers.
+const OCMConfigKey = ".ocmconfig"
+
+// DockerConfigSecret returns a kubernetes.io/dockerconfigjson secret with credentials for registry.
+func DockerConfigSecret(name, namespace, registry, username, password string) (*v1.Secret, error) {
+	auth := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+
+	config, err := json.Marshal(map[string]any{
+		"auths": map[string]any{
+			registry: map[string]string{
+				"username": username,
+				"password": password,
+				"auth":     auth,
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal docker config: %w", err)
+	}
+
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Type:       v1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{v1.DockerConfigJsonKey: config},
+	}, nil
+}
+
+// OCMRegistryCredentials are credentials for an OCI registry in an OCM configuration.
+type OCMRegistryCredentials struct {
+	// Hostname of the registry, without scheme.
+	Hostname string
+	// Port of the registry. Optional.
+	Port     string
+	Username string
+	Password string
+}
+
+// OCMConfigSecret returns a secret with an OCM configuration under OCMConfigKey that contains the given
+// registry credentials.
+func OCMConfigSecret(name, namespace string, credentials ...OCMRegistryCredentials) (*v1.Secret, error) {
+	consumers := make([]any, 0, len(credentials))
+
+	for _, c := range credentials {
+		identity := map[string]string{
+			"type":     "OCIRegistry",
+			"hostname": c.Hostname,
+		}
+
+		if c.Port != "" {
+			identity["port"] = c.Port
+		}
+
+		consumers = append(consumers, map[string]any{
+			"identity": identity,
+			"credentials": []any{
+				map[string]any{
+					"type": "Credentials",
+					"properties": map[string]string{
+						"username": c.Username,
+						"password": c.Password,
+					},
+				},
+			},
+		})
+	}
+
+	config, err := yaml.Marshal(map[string]any{
+		"type": "generic.config.ocm.software/v1",
+		"configurations": []any{
+			map[string]any{
+				"type":      "credentials.config.ocm.software",
+				"consumers": consumers,
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal ocm config: %w", err)
+	}
+
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Data:       map[string][]byte{OCMConfigKey: config},
+	}, nil
+}
+
+// GitBasicAuthSecret returns a secret with username and password for Git over HTTP.
+func GitBasicAuthSecret(name, namespace, username, password string) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Type:       v1.SecretTypeBasicAuth,
+		StringData: map[string]string{
+			v1.BasicAuthUsernameKey: username,
+			v1.BasicAuthPasswordKey: password,
+		},
+	}
+}
+
+// GitBearerTokenSecret returns a secret with a bearer token for Git over HTTP, such as TestUserToken.
+func GitBearerTokenSecret(name, namespace, token string) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		StringData: map[string]string{"bearerToken": token},
+	}
+}
+
+// GitSSHSecret returns a secret with a private key and the known hosts for Git over SSH.
+func GitSSHSecret(name, namespace string, privateKey, knownHosts []byte) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Data: map[string][]byte{
+			"identity":    privateKey,
+			"known_hosts": knownHosts,
+		},
+	}
+}
+
+// SigningPublicKeySecret returns a secret with the public key used to verify the signature with the given name
+// of a component version. The key is stored under the signature name.
+func SigningPublicKeySecret(name, namespace, signatureName string, publicKey []byte) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Data:       map[string][]byte{signatureName: publicKey},
+	}
+}
@@ -5,12 +5,15 @@
 package shared
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"sigs.k8s.io/e2e-framework/pkg/features"
 	"testing"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/e2e-framework/klient/k8s"
@@ -19,7 +22,8 @@ import (
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
 )
 
-// Creates a secret
+// CreateSecret creates a secret. If namespace is empty, the test namespace from the context or the namespace of the
+// config is used.
 func CreateSecret(name string, data map[string][]byte, stringData map[string]string, namespace string) features.Func {
 	return func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
 		clientset, err := kubernetes.NewForConfig(config.Client().RESTConfig())
@@ -27,26 +31,27 @@ func CreateSecret(name string, data map[string][]byte, stringData map[string]str
 			t.Fatal(err)
 			return ctx
 		}
-		if len(namespace) == 0 {
-			namespace = config.Namespace()
+		ns := namespace
+		if len(ns) == 0 {
+			ns = secretNamespace(ctx, config)
 		}
 		secret := &v1.Secret{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      name,
-				Namespace: namespace,
+				Namespace: ns,
 			},
 			Data:       data,
 			StringData: stringData,
 		}
 
-		_, err = clientset.CoreV1().Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{})
+		_, err = clientset.CoreV1().Secrets(ns).Create(ctx, secret, metav1.CreateOptions{})
 		if err != nil {
 			t.Fatal(err)
 			return ctx
 		}
 
 		newSecret := v1.Secret{
-			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns},
 		}
 		err = wait.For(conditions.New(config.Client().Resources()).ResourceMatch(&newSecret, func(object k8s.Object) bool {
 			_, ok := object.(*v1.Secret)
@@ -63,29 +68,201 @@ func CreateSecret(name string, data map[string][]byte, stringData map[string]str
 	}
 }
 
-// DeleteSecret Deletes a secret
+// DeleteSecret deletes a secret created by CreateSecret without a namespace. See DeleteSecretInNamespace.
 func DeleteSecret(name string) features.Func {
+	return DeleteSecretInNamespace(name, "")
+}
+
+// DeleteSecretInNamespace deletes a secret if it exists and waits until it's gone. If namespace is empty, the test
+// namespace from the context or the namespace of the config is used.
+func DeleteSecretInNamespace(name, namespace string) features.Func {
 	return func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
-		clientset, err := kubernetes.NewForConfig(config.Client().RESTConfig())
-		if err != nil {
+		t.Helper()
+
+		ns := namespace
+		if ns == "" {
+			ns = secretNamespace(ctx, config)
+		}
+
+		secret := v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns},
+		}
+
+		if err := config.Client().Resources().Delete(ctx, &secret); err != nil {
+			if apierrors.IsNotFound(err) {
+				return ctx
+			}
+
 			t.Fatal(err)
-			return ctx
 		}
 
-		err = clientset.CoreV1().Secrets(config.Namespace()).Delete(ctx, name, metav1.DeleteOptions{})
+		err := wait.For(conditions.New(config.Client().Resources()).ResourceDeleted(&secret), wait.WithTimeout(time.Minute*2))
 		if err != nil {
 			t.Fatal(err)
-			return ctx
 		}
 
-		secret := v1.Secret{
-			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: config.Namespace()},
+		return ctx
+	}
+}
+
+// CreateOrUpdateSecrets creates the given secrets, or replaces the content of secrets that already exist.
+// Secrets without a namespace are created in the test namespace from the context or the namespace of the config.
+// Unlike CreateSecrets, they are not deleted when the feature ends.
+func CreateOrUpdateSecrets(secrets ...*v1.Secret) features.Func {
+	return func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+		t.Helper()
+
+		for _, secret := range secrets {
+			secret := secret.DeepCopy()
+			if secret.Namespace == "" {
+				secret.Namespace = secretNamespace(ctx, config)
+			}
+
+			if err := createOrUpdateSecret(ctx, config, secret); err != nil {
+				t.Fatal(err)
+			}
+
+			t.Logf("applied secret %s/%s", secret.Namespace, secret.Name)
 		}
-		err = wait.For(conditions.New(config.Client().Resources()).ResourceDeleted(&secret), wait.WithTimeout(time.Minute*2))
-		if err != nil {
-			t.Fatal(err)
+
+		return ctx
+	}
+}
+
+// RotateSecret replaces the content of an existing secret mid-test, for example to check that a controller picks
+// up new credentials. The new content is given like for CreateSecret. It waits until the secret has the new content.
+func RotateSecret(name string, data map[string][]byte, stringData map[string]string, namespace string) features.Func {
+	return func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+		t.Helper()
+
+		ns := namespace
+		if ns == "" {
+			ns = secretNamespace(ctx, config)
+		}
+
+		secret := &v1.Secret{}
+		if err := config.Client().Resources().Get(ctx, name, ns, secret); err != nil {
+			t.Fatal(fmt.Errorf("failed to get secret %s/%s to rotate: %w", ns, name, err))
+		}
+
+		secret.Data = data
+		secret.StringData = stringData
+
+		if err := config.Client().Resources().Update(ctx, secret); err != nil {
+			t.Fatal(fmt.Errorf("failed to rotate secret %s/%s: %w", ns, name, err))
 		}
 
+		expected := map[string][]byte{}
+		for k, v := range data {
+			expected[k] = v
+		}
+
+		for k, v := range stringData {
+			expected[k] = []byte(v)
+		}
+
+		rotated := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns}}
+		if err := wait.For(conditions.New(config.Client().Resources()).ResourceMatch(rotated, func(object k8s.Object) bool {
+			s, ok := object.(*v1.Secret)
+
+			return ok && secretDataEqual(s.Data, expected)
+		}), wait.WithTimeout(time.Minute*2)); err != nil {
+			t.Fatal(fmt.Errorf("secret %s/%s wasn't rotated: %w", ns, name, err))
+		}
+
+		t.Logf("rotated secret %s/%s", ns, name)
+
 		return ctx
 	}
-}
\ No newline at end of file
+}
+
+func createOrUpdateSecret(ctx context.Context, config *envconf.Config, secret *v1.Secret) error {
+	err := config.Client().Resources().Create(ctx, secret)
+	if err == nil {
+		return nil
+	}
+
+	if !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create secret %s/%s: %w", secret.Namespace, secret.Name, err)
+	}
+
+	existing := &v1.Secret{}
+	if err := config.Client().Resources().Get(ctx, secret.Name, secret.Namespace, existing); err != nil {
+		return fmt.Errorf("failed to get secret %s/%s: %w", secret.Namespace, secret.Name, err)
+	}
+
+	// the type of a secret is immutable
+	if secret.Type != "" && secret.Type != existing.Type {
+		return fmt.Errorf("secret %s/%s has type %s instead of %s", secret.Namespace, secret.Name, existing.Type, secret.Type)
+	}
+
+	existing.Data = secret.Data
+	existing.StringData = secret.StringData
+
+	if err := config.Client().Resources().Update(ctx, existing); err != nil {
+		return fmt.Errorf("failed to update secret %s/%s: %w", secret.Namespace, secret.Name, err)
+	}
+
+	return nil
+}
+
+func secretDataEqual(a, b map[string][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for k, v := range a {
+		if other, ok := b[k]; !ok || !bytes.Equal(v, other) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// CreateSecrets creates the given secrets and waits until they exist. Secrets without a namespace are created
+// in the test namespace from the context or the namespace of the config. They are deleted when the feature ends.
+func CreateSecrets(secrets ...*v1.Secret) features.Func {
+	return func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+		t.Helper()
+
+		for _, secret := range secrets {
+			secret := secret.DeepCopy()
+			if secret.Namespace == "" {
+				secret.Namespace = secretNamespace(ctx, config)
+			}
+
+			if err := config.Client().Resources().Create(ctx, secret); err != nil {
+				t.Fatal(fmt.Errorf("failed to create secret %s/%s: %w", secret.Namespace, secret.Name, err))
+			}
+
+			cleanupCtx := context.WithoutCancel(ctx)
+			t.Cleanup(func() {
+				if err := config.Client().Resources().Delete(cleanupCtx, secret); err != nil && !apierrors.IsNotFound(err) {
+					t.Errorf("failed to delete secret %s/%s: %v", secret.Namespace, secret.Name, err)
+				}
+			})
+
+			created := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: secret.Name, Namespace: secret.Namespace}}
+			if err := wait.For(
+				conditions.New(config.Client().Resources()).ResourceMatch(created, func(k8s.Object) bool { return true }),
+				wait.WithTimeout(time.Minute*2),
+			); err != nil {
+				t.Fatal(fmt.Errorf("secret %s/%s wasn't created: %w", secret.Namespace, secret.Name, err))
+			}
+
+			t.Logf("created secret %s/%s", secret.Namespace, secret.Name)
+		}
+
+		return ctx
+	}
+}
+
+// secretNamespace returns the test namespace from the context, or the namespace of the config.
+func secretNamespace(ctx context.Context, config *envconf.Config) string {
+	if namespace, ok := TestNamespaceFromContext(ctx); ok {
+		return namespace
+	}
+
+	return config.Namespace()
+}
@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package assess
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"code.gitea.io/sdk/gitea"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+
+	"github.com/open-component-model/ocm-e2e-framework/shared"
+)
+
+// CodeOwners contains the expected owners listed in a repository's CODEOWNERS file.
+// If Owner is empty, the test user is used.
+type CodeOwners struct {
+	Owner      string
+	Repository string
+	Path       string
+	Owners     []string
+}
+
+// CheckCodeOwners adds a check to verify that the CODEOWNERS file of a repository lists the expected owners.
+// Owners are matched against the whitespace separated entries of the file, so "@org/team" and "@user" both work.
+func CheckCodeOwners(codeOwners ...CodeOwners) features.Func {
+	return func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+		t.Helper()
+
+		gclient, err := gitea.NewClient(shared.BaseURL, gitea.SetToken(shared.TestUserToken))
+		if err != nil {
+			t.Fatal(fmt.Errorf("failed to create gitea client: %w", err))
+		}
+
+		for _, c := range codeOwners {
+			owner := shared.Owner
+			if c.Owner != "" {
+				owner = c.Owner
+			}
+
+			path := "CODEOWNERS"
+			if c.Path != "" {
+				path = c.Path
+			}
+
+			content, _, err := gclient.GetFile(owner, c.Repository, "main", path)
+			if err != nil {
+				t.Fatal(fmt.Errorf("failed to find expected file %s/%s/%s with error: %w", owner, c.Repository, path, err))
+			}
+
+			entries := map[string]struct{}{}
+			for _, field := range strings.Fields(string(content)) {
+				entries[field] = struct{}{}
+			}
+
+			for _, expected := range c.Owners {
+				if _, ok := entries[expected]; !ok {
+					t.Fatalf("expected code owner '%s' not found in %s/%s/%s: '%s'", expected, owner, c.Repository, path, string(content))
+				}
+			}
+		}
+
+		return ctx
+	}
+}
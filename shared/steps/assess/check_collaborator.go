@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package assess
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"code.gitea.io/sdk/gitea"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+
+	"github.com/open-component-model/ocm-e2e-framework/shared"
+)
+
+// Collaborator contains details about a collaborator that needs to be checked on a repository.
+// If Owner is empty, the test user is used. If Permission is empty, only membership is checked.
+type Collaborator struct {
+	Owner      string
+	Repository string
+	Username   string
+	Permission gitea.AccessMode
+}
+
+// CheckCollaborators adds a check to verify that users are collaborators of a repository with the expected permission.
+func CheckCollaborators(collaborators ...Collaborator) features.Func {
+	return func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+		t.Helper()
+
+		gclient, err := gitea.NewClient(shared.BaseURL, gitea.SetToken(shared.TestUserToken))
+		if err != nil {
+			t.Fatal(fmt.Errorf("failed to create gitea client: %w", err))
+		}
+
+		for _, c := range collaborators {
+			owner := shared.Owner
+			if c.Owner != "" {
+				owner = c.Owner
+			}
+
+			ok, _, err := gclient.IsCollaborator(owner, c.Repository, c.Username)
+			if err != nil {
+				t.Fatal(fmt.Errorf("failed to check collaborator %s on %s/%s: %w", c.Username, owner, c.Repository, err))
+			}
+
+			if !ok {
+				t.Fatalf("user %s is not a collaborator of %s/%s", c.Username, owner, c.Repository)
+			}
+
+			if c.Permission == "" {
+				continue
+			}
+
+			result, _, err := gclient.CollaboratorPermission(owner, c.Repository, c.Username)
+			if err != nil || result == nil {
+				t.Fatal(fmt.Errorf("failed to get permission of %s on %s/%s: %w", c.Username, owner, c.Repository, err))
+			}
+
+			if result.Permission != c.Permission {
+				t.Fatalf("expected permission '%s' for %s on %s/%s did not equal actual: '%s'",
+					c.Permission, c.Username, owner, c.Repository, result.Permission)
+			}
+		}
+
+		return ctx
+	}
+}
+
+// CheckTeamMembers adds a check to verify that the given users are members of a team in an organization.
+func CheckTeamMembers(org, team string, members ...string) features.Func {
+	return func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+		t.Helper()
+
+		gclient, err := gitea.NewClient(shared.BaseURL, gitea.SetToken(shared.TestUserToken))
+		if err != nil {
+			t.Fatal(fmt.Errorf("failed to create gitea client: %w", err))
+		}
+
+		teams, _, err := gclient.SearchOrgTeams(org, &gitea.SearchTeamsOptions{Query: team})
+		if err != nil {
+			t.Fatal(fmt.Errorf("failed to search teams in organization %s: %w", org, err))
+		}
+
+		var found *gitea.Team
+
+		for _, tm := range teams {
+			if tm.Name == team {
+				found = tm
+
+				break
+			}
+		}
+
+		if found == nil {
+			t.Fatalf("team %s not found in organization %s", team, org)
+		}
+
+		for _, member := range members {
+			if _, _, err := gclient.GetTeamMember(found.ID, member); err != nil {
+				t.Fatal(fmt.Errorf("user %s is not a member of team %s/%s: %w", member, org, team, err))
+			}
+		}
+
+		return ctx
+	}
+}
@@ -17,10 +17,13 @@ import (
 )
 
 // File contains details about a file that needs to be checked in a repository.
+// If LFS is set, the file is expected to be an LFS pointer and Content is compared
+// against the object it points to.
 type File struct {
 	Repository string
 	Path       string
 	Content    string
+	LFS        bool
 }
 
 // CheckRepoFileContent adds a check to verify that content of a pushed file has the expected content.
@@ -40,6 +43,17 @@ func CheckRepoFileContent(files ...File) features.Func {
 				t.Fatal(fmt.Errorf("failed to find expected file %s/%s with error: %w", file.Repository, file.Path, err))
 			}
 
+			if file.LFS {
+				if !shared.IsLFSPointer(content) {
+					t.Fatalf("expected file %s/%s to be an lfs pointer, got: '%s'", file.Repository, file.Path, string(content))
+				}
+
+				content, _, err = gclient.GetFile(shared.Owner, file.Repository, "main", file.Path, true)
+				if err != nil {
+					t.Fatal(fmt.Errorf("failed to fetch lfs object for %s/%s with error: %w", file.Repository, file.Path, err))
+				}
+			}
+
 			if file.Content != string(content) {
 				t.Fatalf("expected content '%s' did not equal actual: '%s'", file.Content, string(content))
 			}
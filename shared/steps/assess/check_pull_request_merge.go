@@ -0,0 +1,117 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package assess
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"code.gitea.io/sdk/gitea"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+
+	"github.com/open-component-model/ocm-e2e-framework/shared"
+)
+
+// CheckPullRequestMergeBlocked adds a check to verify that a pull request can't be merged without force,
+// for example because required approvals or status checks are missing. Gitea has to refuse the merge with
+// 405 Method Not Allowed or 409 Conflict, any other failure fails the check.
+func CheckPullRequestMergeBlocked(repoName string, number int) features.Func {
+	return func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+		t.Helper()
+
+		gclient, err := gitea.NewClient(shared.BaseURL, gitea.SetToken(shared.TestUserToken))
+		if err != nil {
+			t.Fatal(fmt.Errorf("failed to create gitea client: %w", err))
+		}
+
+		merged, resp, err := gclient.MergePullRequest(shared.Owner, repoName, int64(number), gitea.MergePullRequestOption{
+			Style:   gitea.MergeStyleSquash,
+			Title:   "Merge attempt on protected branch",
+			Message: "This merge is expected to be blocked",
+		})
+		if err != nil {
+			t.Fatal(fmt.Errorf("failed to attempt merge of pull request %d for repo %s: %w", number, repoName, err))
+		}
+
+		if merged {
+			t.Fatalf("expected merge of pull request %d for repo %s to be blocked, but it was merged", number, repoName)
+		}
+
+		// any other status, such as 404 for a wrong pull request or 403 for a wrong token, isn't a protection block
+		if resp == nil || (resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusConflict) {
+			t.Fatalf("expected merge of pull request %d for repo %s to be blocked by branch protection, got %s",
+				number, repoName, responseStatus(resp))
+		}
+
+		t.Logf("merge of pull request %d for repo %s was blocked with status %d", number, repoName, resp.StatusCode)
+
+		return ctx
+	}
+}
+
+func responseStatus(resp *gitea.Response) string {
+	if resp == nil {
+		return "no response"
+	}
+
+	return resp.Status
+}
+
+// CheckPullRequestMerged adds a check to verify the merge state of a pull request. Use merged=false
+// to make sure a controller didn't merge a pull request that branch protection should have blocked.
+func CheckPullRequestMerged(repoName string, number int, merged bool) features.Func {
+	return func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+		t.Helper()
+
+		gclient, err := gitea.NewClient(shared.BaseURL, gitea.SetToken(shared.TestUserToken))
+		if err != nil {
+			t.Fatal(fmt.Errorf("failed to create gitea client: %w", err))
+		}
+
+		pr, _, err := gclient.GetPullRequest(shared.Owner, repoName, int64(number))
+		if err != nil {
+			t.Fatal(fmt.Errorf("pull request with number %d not found for repo %s: %w", number, repoName, err))
+		}
+
+		if pr.HasMerged != merged {
+			t.Fatalf("expected pull request %d for repo %s to have merged state %t, got %t (state: %s)",
+				number, repoName, merged, pr.HasMerged, pr.State)
+		}
+
+		return ctx
+	}
+}
+
+// CheckCommitStatus adds a check to verify the combined commit status of the head of a pull request.
+func CheckCommitStatus(repoName string, number int, state gitea.StatusState) features.Func {
+	return func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+		t.Helper()
+
+		gclient, err := gitea.NewClient(shared.BaseURL, gitea.SetToken(shared.TestUserToken))
+		if err != nil {
+			t.Fatal(fmt.Errorf("failed to create gitea client: %w", err))
+		}
+
+		pr, _, err := gclient.GetPullRequest(shared.Owner, repoName, int64(number))
+		if err != nil {
+			t.Fatal(fmt.Errorf("pull request with number %d not found for repo %s: %w", number, repoName, err))
+		}
+
+		status, _, err := gclient.GetCombinedStatus(shared.Owner, repoName, pr.Head.Sha)
+		if err != nil {
+			t.Fatal(fmt.Errorf("failed to get combined status for %s@%s: %w", repoName, pr.Head.Sha, err))
+		}
+
+		if status.State != state {
+			t.Fatalf("expected combined status '%s' for pull request %d of repo %s did not equal actual: '%s'",
+				state, number, repoName, status.State)
+		}
+
+		return ctx
+	}
+}
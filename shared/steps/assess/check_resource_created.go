@@ -12,9 +12,12 @@ import (
 	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
 	"sigs.k8s.io/e2e-framework/pkg/features"
+
+	"github.com/open-component-model/ocm-e2e-framework/shared"
 )
 
 // Object contains information about the object to check existence on.
+// If Namespace is empty, the namespace created by setup.CreateTestNamespace is used.
 type Object struct {
 	Name      string
 	Namespace string
@@ -33,7 +36,7 @@ func ResourceWasCreated(objs ...Object) features.Func {
 		}
 
 		for _, obj := range objs {
-			if err := r.Get(ctx, obj.Name, obj.Namespace, obj.Obj); err != nil {
+			if err := r.Get(ctx, obj.Name, shared.NamespaceOrDefault(ctx, obj.Namespace), obj.Obj); err != nil {
 				t.Fatal(err)
 			}
 		}
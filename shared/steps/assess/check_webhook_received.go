@@ -0,0 +1,122 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package assess
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+
+	"github.com/open-component-model/ocm-e2e-framework/shared"
+)
+
+// giteaEvent contains the fields of a Gitea webhook payload that are checked.
+type giteaEvent struct {
+	Ref        string `json:"ref"`
+	Repository struct {
+		Name     string `json:"name"`
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// fluxEvent contains the fields of a Flux notification-controller event that are checked.
+type fluxEvent struct {
+	InvolvedObject struct {
+		Kind      string `json:"kind"`
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"involvedObject"`
+	Severity string `json:"severity"`
+	Reason   string `json:"reason"`
+	Message  string `json:"message"`
+}
+
+// Alert contains details about a Flux alert that is expected at a webhook receiver. Empty fields match anything.
+type Alert struct {
+	Kind      string
+	Name      string
+	Namespace string
+	Severity  string
+	Reason    string
+}
+
+// CheckGitEventReceived adds a check to verify that the webhook receiver got a Gitea event of the given
+// type, such as push or pull_request, for a repository.
+func CheckGitEventReceived(receiverName, eventType, repo string) features.Func {
+	return func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+		t.Helper()
+
+		waitForWebhookEvent(ctx, t, receiverName, func(event shared.WebhookEvent) bool {
+			if event.Header.Get("X-Gitea-Event") != eventType {
+				return false
+			}
+
+			payload := giteaEvent{}
+			if err := json.Unmarshal(event.Body, &payload); err != nil {
+				return false
+			}
+
+			return payload.Repository.Name == repo || payload.Repository.FullName == repo
+		})
+
+		t.Logf("received %s event for repository %s", eventType, repo)
+
+		return ctx
+	}
+}
+
+// CheckAlertReceived adds a check to verify that the webhook receiver got a Flux alert matching the given details.
+func CheckAlertReceived(receiverName string, alert Alert) features.Func {
+	return func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+		t.Helper()
+
+		waitForWebhookEvent(ctx, t, receiverName, func(event shared.WebhookEvent) bool {
+			payload := fluxEvent{}
+			if err := json.Unmarshal(event.Body, &payload); err != nil {
+				return false
+			}
+
+			return matches(alert.Kind, payload.InvolvedObject.Kind) &&
+				matches(alert.Name, payload.InvolvedObject.Name) &&
+				matches(alert.Namespace, payload.InvolvedObject.Namespace) &&
+				matches(alert.Severity, payload.Severity) &&
+				matches(alert.Reason, payload.Reason)
+		})
+
+		t.Logf("received alert %+v", alert)
+
+		return ctx
+	}
+}
+
+func waitForWebhookEvent(ctx context.Context, t *testing.T, receiverName string, match func(event shared.WebhookEvent) bool) {
+	t.Helper()
+
+	receiver, err := shared.WebhookReceiverFromContext(ctx, receiverName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := wait.For(func(ctx context.Context) (bool, error) {
+		_, found := receiver.Find(match)
+
+		return found, nil
+	}, wait.WithTimeout(time.Minute*2), wait.WithInterval(time.Second*2)); err != nil {
+		for _, event := range receiver.Events() {
+			t.Logf("recorded event on %s: %s", event.Path, string(event.Body))
+		}
+
+		t.Fatalf("expected event not received by webhook receiver %s: %s", receiverName, err)
+	}
+}
+
+func matches(expected, actual string) bool {
+	return expected == "" || expected == actual
+}
@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package setup
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"code.gitea.io/sdk/gitea"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+
+	"github.com/open-component-model/ocm-e2e-framework/shared"
+)
+
+// BranchProtection contains information about a branch protection rule to configure on a repository.
+// If Owner is empty, the test user is used. If Branch is empty, main is protected.
+//
+// Gitea always rejects force pushes to a protected branch. EnablePush controls whether regular pushes
+// are still allowed, optionally restricted to PushWhitelistUsernames.
+type BranchProtection struct {
+	Owner                  string
+	Repository             string
+	Branch                 string
+	RequiredApprovals      int64
+	StatusCheckContexts    []string
+	EnablePush             bool
+	PushWhitelistUsernames []string
+	BlockOnRejectedReviews bool
+	BlockOnOutdatedBranch  bool
+}
+
+// AddBranchProtection configures branch protection rules on repositories.
+func AddBranchProtection(protections ...BranchProtection) features.Func {
+	return func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+		t.Helper()
+
+		gclient, err := gitea.NewClient(shared.BaseURL, gitea.SetToken(shared.TestUserToken))
+		if err != nil {
+			t.Fatal(fmt.Errorf("failed to create gitea client: %w", err))
+		}
+
+		for _, p := range protections {
+			owner, branch := protectionTarget(p)
+
+			if _, _, err := gclient.CreateBranchProtection(owner, p.Repository, gitea.CreateBranchProtectionOption{
+				BranchName:             branch,
+				RuleName:               branch,
+				EnablePush:             p.EnablePush,
+				EnablePushWhitelist:    len(p.PushWhitelistUsernames) > 0,
+				PushWhitelistUsernames: p.PushWhitelistUsernames,
+				EnableStatusCheck:      len(p.StatusCheckContexts) > 0,
+				StatusCheckContexts:    p.StatusCheckContexts,
+				RequiredApprovals:      p.RequiredApprovals,
+				BlockOnRejectedReviews: p.BlockOnRejectedReviews,
+				BlockOnOutdatedBranch:  p.BlockOnOutdatedBranch,
+			}); err != nil {
+				t.Fatal(fmt.Errorf("failed to protect branch %s of %s/%s: %w", branch, owner, p.Repository, err))
+			}
+
+			t.Logf("successfully protected branch %s of %s/%s", branch, owner, p.Repository)
+		}
+
+		return ctx
+	}
+}
+
+// DeleteBranchProtection removes branch protection rules from repositories.
+func DeleteBranchProtection(protections ...BranchProtection) features.Func {
+	return func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+		t.Helper()
+
+		gclient, err := gitea.NewClient(shared.BaseURL, gitea.SetToken(shared.TestUserToken))
+		if err != nil {
+			t.Fatal(fmt.Errorf("failed to create gitea client: %w", err))
+		}
+
+		for _, p := range protections {
+			owner, branch := protectionTarget(p)
+
+			if _, err := gclient.DeleteBranchProtection(owner, p.Repository, branch); err != nil {
+				t.Fatal(fmt.Errorf("failed to remove protection of branch %s of %s/%s: %w", branch, owner, p.Repository, err))
+			}
+		}
+
+		return ctx
+	}
+}
+
+func protectionTarget(p BranchProtection) (string, string) {
+	owner := shared.Owner
+	if p.Owner != "" {
+		owner = p.Owner
+	}
+
+	branch := "main"
+	if p.Branch != "" {
+		branch = p.Branch
+	}
+
+	return owner, branch
+}
@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package setup
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"code.gitea.io/sdk/gitea"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+
+	"github.com/open-component-model/ocm-e2e-framework/shared"
+)
+
+// Collaborator contains information about a user to add to a repository with a specific permission.
+// If Owner is empty, the test user is used. If Permission is empty, write access is granted.
+type Collaborator struct {
+	Owner      string
+	Repository string
+	Username   string
+	Permission gitea.AccessMode
+}
+
+// AddCollaborators adds users as collaborators to repositories.
+func AddCollaborators(collaborators ...Collaborator) features.Func {
+	return func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+		t.Helper()
+
+		gclient, err := gitea.NewClient(shared.BaseURL, gitea.SetToken(shared.TestUserToken))
+		if err != nil {
+			t.Fatal(fmt.Errorf("failed to create gitea client: %w", err))
+		}
+
+		for _, c := range collaborators {
+			owner := shared.Owner
+			if c.Owner != "" {
+				owner = c.Owner
+			}
+
+			permission := gitea.AccessModeWrite
+			if c.Permission != "" {
+				permission = c.Permission
+			}
+
+			if _, err := gclient.AddCollaborator(owner, c.Repository, c.Username, gitea.AddCollaboratorOption{
+				Permission: &permission,
+			}); err != nil {
+				t.Fatal(fmt.Errorf("failed to add collaborator %s to %s/%s: %w", c.Username, owner, c.Repository, err))
+			}
+
+			t.Logf("successfully added %s to %s/%s with permission %s", c.Username, owner, c.Repository, permission)
+		}
+
+		return ctx
+	}
+}
@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package setup
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"code.gitea.io/sdk/gitea"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+
+	"github.com/open-component-model/ocm-e2e-framework/shared"
+)
+
+// CommitStatus contains information about a commit status to post. The commit is either the head of
+// PullRequest, if set, or Ref, which can be a branch name or a commit SHA. If both are empty, the
+// head of main is used. If Owner is empty, the test user is used.
+type CommitStatus struct {
+	Owner       string
+	Repository  string
+	PullRequest int
+	Ref         string
+	Context     string
+	State       gitea.StatusState
+	Description string
+	TargetURL   string
+}
+
+// AddCommitStatuses posts commit statuses, for example to satisfy or fail required status checks.
+func AddCommitStatuses(statuses ...CommitStatus) features.Func {
+	return func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+		t.Helper()
+
+		gclient, err := gitea.NewClient(shared.BaseURL, gitea.SetToken(shared.TestUserToken))
+		if err != nil {
+			t.Fatal(fmt.Errorf("failed to create gitea client: %w", err))
+		}
+
+		for _, s := range statuses {
+			owner := shared.Owner
+			if s.Owner != "" {
+				owner = s.Owner
+			}
+
+			sha, err := resolveCommitSHA(gclient, owner, s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, _, err := gclient.CreateStatus(owner, s.Repository, sha, gitea.CreateStatusOption{
+				State:       s.State,
+				Context:     s.Context,
+				Description: s.Description,
+				TargetURL:   s.TargetURL,
+			}); err != nil {
+				t.Fatal(fmt.Errorf("failed to create status %s on %s/%s@%s: %w", s.Context, owner, s.Repository, sha, err))
+			}
+
+			t.Logf("successfully set status %s to %s on %s/%s@%s", s.Context, s.State, owner, s.Repository, sha)
+		}
+
+		return ctx
+	}
+}
+
+func resolveCommitSHA(gclient *gitea.Client, owner string, s CommitStatus) (string, error) {
+	if s.PullRequest > 0 {
+		pr, _, err := gclient.GetPullRequest(owner, s.Repository, int64(s.PullRequest))
+		if err != nil {
+			return "", fmt.Errorf("pull request with number %d not found for repo %s: %w", s.PullRequest, s.Repository, err)
+		}
+
+		return pr.Head.Sha, nil
+	}
+
+	ref := "main"
+	if s.Ref != "" {
+		ref = s.Ref
+	}
+
+	branch, _, err := gclient.GetRepoBranch(owner, s.Repository, ref)
+	if err != nil {
+		// not a branch, assume it's a commit SHA
+		return ref, nil //nolint:nilerr // the ref is used as is
+	}
+
+	return branch.Commit.ID, nil
+}
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"code.gitea.io/sdk/gitea"
@@ -20,8 +21,10 @@ import (
 )
 
 // File in setup package contain information about files that have to be created during setup phase.
+// If LFS is set, the file is stored in the Git LFS store of the repository and tracked in .gitattributes.
 type File struct {
 	RepoName, SourceFilepath, DestFilepath string
+	LFS                                    bool
 }
 
 // AddFilesToGitRepository adds files to a git repository.
@@ -37,14 +40,27 @@ func AddFilesToGitRepository(files ...File) features.Func {
 		for _, file := range files {
 			data, err := os.ReadFile(filepath.Join("./testdata", file.SourceFilepath))
 			if err != nil {
-				return nil
+				t.Fatal(fmt.Errorf("failed to read file %s: %w", file.SourceFilepath, err))
+			}
+
+			if file.LFS {
+				if err := trackLFSPath(gclient, file.RepoName, file.DestFilepath); err != nil {
+					t.Fatal(err)
+				}
+
+				pointer, err := shared.UploadLFSObject(shared.Owner, file.RepoName, data)
+				if err != nil {
+					t.Fatal(fmt.Errorf("failed to upload lfs object for %s: %w", file.DestFilepath, err))
+				}
+
+				data = []byte(pointer)
 			}
 
 			_, _, err = gclient.CreateFile(shared.Owner, file.RepoName, file.DestFilepath, gitea.CreateFileOptions{
 				Content: base64.StdEncoding.EncodeToString(data),
 			})
 			if err != nil {
-				t.Fatal(fmt.Errorf("failed to add file to repository %s: %w", file, err))
+				t.Fatal(fmt.Errorf("failed to add file %s to repository %s: %w", file.DestFilepath, file.RepoName, err))
 			}
 
 			t.Logf("successfully added %s to repository %s", file.DestFilepath, file.RepoName)
@@ -53,3 +69,51 @@ func AddFilesToGitRepository(files ...File) features.Func {
 		return ctx
 	}
 }
+
+// trackLFSPath adds path to the .gitattributes file of the repository as an LFS tracked file.
+func trackLFSPath(gclient *gitea.Client, repoName, path string) error {
+	const attributesFile = ".gitattributes"
+
+	entry := fmt.Sprintf("%s filter=lfs diff=lfs merge=lfs -text", path)
+
+	contents, _, err := gclient.GetContents(shared.Owner, repoName, "main", attributesFile)
+	if err != nil {
+		_, _, err = gclient.CreateFile(shared.Owner, repoName, attributesFile, gitea.CreateFileOptions{
+			Content: base64.StdEncoding.EncodeToString([]byte(entry + "\n")),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create %s in repository %s: %w", attributesFile, repoName, err)
+		}
+
+		return nil
+	}
+
+	existing := ""
+	if contents.Content != nil {
+		decoded, err := base64.StdEncoding.DecodeString(*contents.Content)
+		if err != nil {
+			return fmt.Errorf("failed to decode %s in repository %s: %w", attributesFile, repoName, err)
+		}
+
+		existing = string(decoded)
+	}
+
+	for _, line := range strings.Split(existing, "\n") {
+		if strings.TrimSpace(line) == entry {
+			return nil
+		}
+	}
+
+	if existing != "" && !strings.HasSuffix(existing, "\n") {
+		existing += "\n"
+	}
+
+	if _, _, err := gclient.UpdateFile(shared.Owner, repoName, attributesFile, gitea.UpdateFileOptions{
+		SHA:     contents.SHA,
+		Content: base64.StdEncoding.EncodeToString([]byte(existing + entry + "\n")),
+	}); err != nil {
+		return fmt.Errorf("failed to update %s in repository %s: %w", attributesFile, repoName, err)
+	}
+
+	return nil
+}
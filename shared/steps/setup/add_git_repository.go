@@ -40,3 +40,28 @@ func AddGitRepository(repoName string) features.Func {
 		return ctx
 	}
 }
+
+// AddOrganizationGitRepository creates a git repository owned by the given organization.
+func AddOrganizationGitRepository(org, repoName string) features.Func {
+	return func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+		t.Helper()
+
+		gclient, err := gitea.NewClient(shared.BaseURL, gitea.SetToken(shared.TestUserToken))
+		if err != nil {
+			t.Fatal(fmt.Errorf("failed to create gitea client: %w", err))
+		}
+
+		repo, _, err := gclient.CreateOrgRepo(org, gitea.CreateRepoOption{
+			AutoInit:      true,
+			Name:          repoName,
+			DefaultBranch: "main",
+		})
+		if err != nil {
+			t.Fatal(fmt.Errorf("failed to create repository in organization %s: %w", org, err))
+		}
+
+		t.Logf("successfully created repository at url %s", repo.CloneURL)
+
+		return ctx
+	}
+}
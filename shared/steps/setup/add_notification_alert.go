@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package setup
+
+import (
+	"context"
+	"testing"
+
+	notificationv1 "github.com/fluxcd/notification-controller/api/v1"
+	notificationv1b3 "github.com/fluxcd/notification-controller/api/v1beta3"
+	"github.com/fluxcd/pkg/apis/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+
+	"github.com/open-component-model/ocm-e2e-framework/shared"
+)
+
+// NotificationAlert contains information about a Flux notification-controller Provider and Alert that
+// forward events to a webhook receiver. If Severity is empty, all events are sent.
+type NotificationAlert struct {
+	Name         string
+	Namespace    string
+	Receiver     string
+	Severity     string
+	EventSources []notificationv1.CrossNamespaceObjectReference
+}
+
+// AddNotificationAlert creates a generic Provider pointing at the webhook receiver started with
+// shared.StartWebhookReceiver and an Alert referencing it. Both are deleted when the feature finishes, even if a
+// step fails, or earlier with DeleteNotificationAlert.
+func AddNotificationAlert(alert NotificationAlert) features.Func {
+	return func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+		t.Helper()
+
+		receiver, err := shared.WebhookReceiverFromContext(ctx, alert.Receiver)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := resources.New(config.Client().RESTConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := notificationv1b3.AddToScheme(r.GetScheme()); err != nil {
+			t.Fatal(err)
+		}
+
+		provider := notificationv1b3.Provider{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      alert.Name,
+				Namespace: alert.Namespace,
+			},
+			Spec: notificationv1b3.ProviderSpec{
+				Type:    notificationv1b3.GenericProvider,
+				Address: receiver.URL + "/alerts",
+			},
+		}
+
+		if err := r.Create(ctx, &provider); err != nil {
+			t.Fatal(err)
+		}
+
+		// teardown steps are skipped if a step fails, so the cleanup keeps the alert out of later features
+		cleanupCtx := context.WithoutCancel(ctx)
+		t.Cleanup(func() {
+			if err := deleteNotificationAlert(cleanupCtx, config, alert); err != nil {
+				t.Error(err)
+			}
+		})
+
+		t.Logf("Created provider %s/%s", alert.Namespace, alert.Name)
+
+		severity := alert.Severity
+		if severity == "" {
+			severity = "info"
+		}
+
+		fluxAlert := notificationv1b3.Alert{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      alert.Name,
+				Namespace: alert.Namespace,
+			},
+			Spec: notificationv1b3.AlertSpec{
+				ProviderRef: meta.LocalObjectReference{
+					Name: provider.GetName(),
+				},
+				EventSeverity: severity,
+				EventSources:  alert.EventSources,
+			},
+		}
+
+		if err := r.Create(ctx, &fluxAlert); err != nil {
+			t.Fatal(err)
+		}
+
+		t.Logf("Created alert %s/%s", alert.Namespace, alert.Name)
+
+		return ctx
+	}
+}
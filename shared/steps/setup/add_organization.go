@@ -0,0 +1,41 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package setup
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"code.gitea.io/sdk/gitea"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+
+	"github.com/open-component-model/ocm-e2e-framework/shared"
+)
+
+// AddOrganization creates an organization owned by the test user.
+func AddOrganization(name string) features.Func {
+	return func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+		t.Helper()
+
+		gclient, err := gitea.NewClient(shared.BaseURL, gitea.SetToken(shared.TestUserToken))
+		if err != nil {
+			t.Fatal(fmt.Errorf("failed to create gitea client: %w", err))
+		}
+
+		org, _, err := gclient.CreateOrg(gitea.CreateOrgOption{
+			Name:       name,
+			Visibility: gitea.VisibleTypePublic,
+		})
+		if err != nil {
+			t.Fatal(fmt.Errorf("failed to create organization %s: %w", name, err))
+		}
+
+		t.Logf("successfully created organization %s", org.UserName)
+
+		return ctx
+	}
+}
@@ -0,0 +1,78 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package setup
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"code.gitea.io/sdk/gitea"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+
+	"github.com/open-component-model/ocm-e2e-framework/shared"
+)
+
+// Team contains information about a team to create in an organization.
+// If Permission is empty, the team is created with read access.
+type Team struct {
+	Organization            string
+	Name                    string
+	Permission              gitea.AccessMode
+	IncludesAllRepositories bool
+	Members                 []string
+	Repositories            []string
+}
+
+// AddTeams creates teams in organizations and adds the given members and repositories to them.
+func AddTeams(teams ...Team) features.Func {
+	return func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+		t.Helper()
+
+		gclient, err := gitea.NewClient(shared.BaseURL, gitea.SetToken(shared.TestUserToken))
+		if err != nil {
+			t.Fatal(fmt.Errorf("failed to create gitea client: %w", err))
+		}
+
+		for _, team := range teams {
+			permission := gitea.AccessModeRead
+			if team.Permission != "" {
+				permission = team.Permission
+			}
+
+			created, _, err := gclient.CreateTeam(team.Organization, gitea.CreateTeamOption{
+				Name:                    team.Name,
+				Permission:              permission,
+				IncludesAllRepositories: team.IncludesAllRepositories,
+				Units: []gitea.RepoUnitType{
+					gitea.RepoUnitCode,
+					gitea.RepoUnitIssues,
+					gitea.RepoUnitPulls,
+					gitea.RepoUnitReleases,
+				},
+			})
+			if err != nil {
+				t.Fatal(fmt.Errorf("failed to create team %s/%s: %w", team.Organization, team.Name, err))
+			}
+
+			for _, member := range team.Members {
+				if _, err := gclient.AddTeamMember(created.ID, member); err != nil {
+					t.Fatal(fmt.Errorf("failed to add member %s to team %s/%s: %w", member, team.Organization, team.Name, err))
+				}
+			}
+
+			for _, repo := range team.Repositories {
+				if _, err := gclient.AddTeamRepository(created.ID, team.Organization, repo); err != nil {
+					t.Fatal(fmt.Errorf("failed to add repository %s to team %s/%s: %w", repo, team.Organization, team.Name, err))
+				}
+			}
+
+			t.Logf("successfully created team %s/%s", team.Organization, team.Name)
+		}
+
+		return ctx
+	}
+}
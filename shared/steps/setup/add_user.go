@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package setup
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"code.gitea.io/sdk/gitea"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+
+	"github.com/open-component-model/ocm-e2e-framework/shared"
+)
+
+// User contains information about a Gitea user to create. If Email is empty, one is generated from the Name.
+type User struct {
+	Name     string
+	Email    string
+	Password string
+}
+
+// AddUsers creates Gitea users through the admin API of the test user. These users can then be
+// used as collaborators or team members.
+func AddUsers(users ...User) features.Func {
+	return func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+		t.Helper()
+
+		gclient, err := gitea.NewClient(shared.BaseURL, gitea.SetToken(shared.TestUserToken))
+		if err != nil {
+			t.Fatal(fmt.Errorf("failed to create gitea client: %w", err))
+		}
+
+		mustChangePassword := false
+
+		for _, user := range users {
+			email := user.Email
+			if email == "" {
+				email = fmt.Sprintf("%s@ocm.software", user.Name)
+			}
+
+			password := user.Password
+			if password == "" {
+				password = "password1234"
+			}
+
+			if _, _, err := gclient.AdminCreateUser(gitea.CreateUserOption{
+				Username:           user.Name,
+				Email:              email,
+				Password:           password,
+				MustChangePassword: &mustChangePassword,
+			}); err != nil {
+				t.Fatal(fmt.Errorf("failed to create user %s: %w", user.Name, err))
+			}
+
+			t.Logf("successfully created user %s", user.Name)
+		}
+
+		return ctx
+	}
+}
+
+// DeleteUsers deletes the given Gitea users.
+func DeleteUsers(names ...string) features.Func {
+	return func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+		t.Helper()
+
+		gclient, err := gitea.NewClient(shared.BaseURL, gitea.SetToken(shared.TestUserToken))
+		if err != nil {
+			t.Fatal(fmt.Errorf("failed to create gitea client: %w", err))
+		}
+
+		for _, name := range names {
+			if _, err := gclient.AdminDeleteUser(name); err != nil {
+				t.Fatal(fmt.Errorf("failed to delete user %s: %w", name, err))
+			}
+		}
+
+		return ctx
+	}
+}
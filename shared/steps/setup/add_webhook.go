@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package setup
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"code.gitea.io/sdk/gitea"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+
+	"github.com/open-component-model/ocm-e2e-framework/shared"
+)
+
+// AddRepositoryWebhook registers a Gitea webhook on a repository of the test user that sends the given
+// events to the webhook receiver started with shared.StartWebhookReceiver. If no events are given,
+// push and pull_request events are sent.
+//
+// Gitea only delivers webhooks to hosts allowed by the [webhook] ALLOWED_HOST_LIST setting, which has to
+// include the address of the receiver. Start the server with shared.WithGiteaConfig to set it.
+func AddRepositoryWebhook(repoName, receiverName string, events ...string) features.Func {
+	return func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+		t.Helper()
+
+		receiver, err := shared.WebhookReceiverFromContext(ctx, receiverName)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(events) == 0 {
+			events = []string{"push", "pull_request"}
+		}
+
+		gclient, err := gitea.NewClient(shared.BaseURL, gitea.SetToken(shared.TestUserToken))
+		if err != nil {
+			t.Fatal(fmt.Errorf("failed to create gitea client: %w", err))
+		}
+
+		hook, _, err := gclient.CreateRepoHook(shared.Owner, repoName, gitea.CreateHookOption{
+			Type: gitea.HookTypeGitea,
+			Config: map[string]string{
+				"url":          receiver.URL + "/gitea",
+				"content_type": "json",
+			},
+			Events: events,
+			Active: true,
+		})
+		if err != nil {
+			t.Fatal(fmt.Errorf("failed to create webhook for repository %s: %w", repoName, err))
+		}
+
+		t.Logf("successfully created webhook %d for repository %s pointing to %s", hook.ID, repoName, receiver.URL)
+
+		return ctx
+	}
+}
@@ -6,16 +6,19 @@ package setup
 
 import (
 	"context"
-	"os"
 	"testing"
 
 	"sigs.k8s.io/e2e-framework/klient/decoder"
 	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
 	"sigs.k8s.io/e2e-framework/pkg/features"
+
+	"github.com/open-component-model/ocm-e2e-framework/shared"
 )
 
 // ApplyTestData takes a pattern and applies that from a testdata location.
+// If namespace is empty, the namespace created by CreateTestNamespace is used. The files are rendered as Go
+// templates with the values of shared.TemplateValues.
 func ApplyTestData(namespace, folder, pattern string) features.Func {
 	return func(ctx context.Context, t *testing.T, c *envconf.Config) context.Context {
 		t.Helper()
@@ -26,11 +29,7 @@ func ApplyTestData(namespace, folder, pattern string) features.Func {
 			t.Fatal(err)
 		}
 
-		if err := decoder.DecodeEachFile(
-			ctx, os.DirFS(folder), pattern,
-			decoder.CreateHandler(r),
-			decoder.MutateNamespace(namespace),
-		); err != nil {
+		if err := decodeTestData(ctx, shared.NamespaceOrDefault(ctx, namespace), folder, pattern, decoder.CreateHandler(r)); err != nil {
 			t.Fatal(err)
 		}
 
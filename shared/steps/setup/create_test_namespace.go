@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package setup
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+
+	"github.com/open-component-model/ocm-e2e-framework/shared"
+)
+
+const namespaceDeletionTimeout = 2 * time.Minute
+
+// CreateTestNamespace creates a namespace with a random name based on prefix and stores it in the context.
+// ApplyTestData, DeleteTestData and the assess steps use it if they are called with an empty namespace. The
+// namespace is deleted when the feature finishes, even if a step fails.
+func CreateTestNamespace(prefix string) features.Func {
+	return func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		t.Helper()
+
+		name := envconf.RandomName(prefix, 32)
+		namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+
+		if err := cfg.Client().Resources().Create(ctx, namespace); err != nil {
+			t.Fatal(fmt.Errorf("failed to create test namespace %s: %w", name, err))
+		}
+
+		t.Logf("created test namespace %s", name)
+
+		// teardown steps are skipped if a step fails, so the cleanup makes sure the namespace doesn't leak
+		cleanupCtx := context.WithoutCancel(ctx)
+		t.Cleanup(func() {
+			if err := deleteTestNamespace(cleanupCtx, cfg, name); err != nil {
+				t.Error(err)
+
+				return
+			}
+
+			t.Logf("deleted test namespace %s", name)
+		})
+
+		return shared.WithTestNamespace(ctx, name)
+	}
+}
+
+// DeleteTestNamespace deletes the namespace created by CreateTestNamespace and waits until it's gone, so
+// finalizers of the objects in it have run. Use it to check that the objects can be deleted, the namespace is
+// deleted at the end of the feature anyway.
+func DeleteTestNamespace() features.Func {
+	return func(ctx context.Context, t *testing.T, cfg *envconf.Config) context.Context {
+		t.Helper()
+
+		name, ok := shared.TestNamespaceFromContext(ctx)
+		if !ok {
+			t.Fatal("no test namespace found in context")
+		}
+
+		if err := deleteTestNamespace(ctx, cfg, name); err != nil {
+			t.Fatal(err)
+		}
+
+		t.Logf("deleted test namespace %s", name)
+
+		return ctx
+	}
+}
+
+// deleteTestNamespace deletes the namespace unless it's already gone and waits until it doesn't exist anymore.
+func deleteTestNamespace(ctx context.Context, cfg *envconf.Config, name string) error {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+
+	if err := cfg.Client().Resources().Delete(ctx, namespace); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+
+		return fmt.Errorf("failed to delete test namespace %s: %w", name, err)
+	}
+
+	if err := wait.For(
+		conditions.New(cfg.Client().Resources()).ResourceDeleted(namespace),
+		wait.WithTimeout(namespaceDeletionTimeout),
+	); err != nil {
+		return fmt.Errorf("test namespace %s wasn't deleted: %w", name, err)
+	}
+
+	return nil
+}
@@ -33,3 +33,21 @@ func DeleteGitRepository(repoName string) features.Func {
 		return ctx
 	}
 }
+
+// DeleteOrganizationGitRepository deletes a git repository owned by the given organization.
+func DeleteOrganizationGitRepository(org, repoName string) features.Func {
+	return func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+		t.Helper()
+
+		gclient, err := gitea.NewClient(shared.BaseURL, gitea.SetToken(shared.TestUserToken))
+		if err != nil {
+			t.Fatal(fmt.Errorf("failed to create gitea client: %w", err))
+		}
+
+		if _, err := gclient.DeleteRepo(org, repoName); err != nil {
+			t.Fatal(fmt.Errorf("failed to delete repository %s/%s: %w", org, repoName, err))
+		}
+
+		return ctx
+	}
+}
@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package setup
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	notificationv1b3 "github.com/fluxcd/notification-controller/api/v1beta3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
+	"sigs.k8s.io/e2e-framework/klient/wait"
+	"sigs.k8s.io/e2e-framework/klient/wait/conditions"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+)
+
+const notificationAlertDeletionTimeout = time.Minute
+
+// DeleteNotificationAlert deletes the Alert and the Provider created by AddNotificationAlert, so they don't
+// forward events of later features. Objects that are already gone are ignored.
+func DeleteNotificationAlert(alert NotificationAlert) features.Func {
+	return func(ctx context.Context, t *testing.T, config *envconf.Config) context.Context {
+		t.Helper()
+
+		if err := deleteNotificationAlert(ctx, config, alert); err != nil {
+			t.Fatal(err)
+		}
+
+		t.Logf("deleted alert and provider %s/%s", alert.Namespace, alert.Name)
+
+		return ctx
+	}
+}
+
+// deleteNotificationAlert deletes the Alert before the Provider it references and waits until both are gone.
+func deleteNotificationAlert(ctx context.Context, config *envconf.Config, alert NotificationAlert) error {
+	r, err := resources.New(config.Client().RESTConfig())
+	if err != nil {
+		return fmt.Errorf("failed to create resource client: %w", err)
+	}
+
+	if err := notificationv1b3.AddToScheme(r.GetScheme()); err != nil {
+		return fmt.Errorf("failed to add notification scheme: %w", err)
+	}
+
+	objects := []k8s.Object{
+		&notificationv1b3.Alert{ObjectMeta: v1.ObjectMeta{Name: alert.Name, Namespace: alert.Namespace}},
+		&notificationv1b3.Provider{ObjectMeta: v1.ObjectMeta{Name: alert.Name, Namespace: alert.Namespace}},
+	}
+
+	for _, obj := range objects {
+		if err := r.Delete(ctx, obj); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+
+			return fmt.Errorf("failed to delete %T %s/%s: %w", obj, alert.Namespace, alert.Name, err)
+		}
+
+		if err := wait.For(
+			conditions.New(r).ResourceDeleted(obj),
+			wait.WithTimeout(notificationAlertDeletionTimeout),
+		); err != nil {
+			return fmt.Errorf("%T %s/%s wasn't deleted: %w", obj, alert.Namespace, alert.Name, err)
+		}
+	}
+
+	return nil
+}
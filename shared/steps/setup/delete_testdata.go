@@ -6,16 +6,19 @@ package setup
 
 import (
 	"context"
-	"os"
 	"testing"
 
 	"sigs.k8s.io/e2e-framework/klient/decoder"
 	"sigs.k8s.io/e2e-framework/klient/k8s/resources"
 	"sigs.k8s.io/e2e-framework/pkg/envconf"
 	"sigs.k8s.io/e2e-framework/pkg/features"
+
+	"github.com/open-component-model/ocm-e2e-framework/shared"
 )
 
 // DeleteTestData takes a pattern and deletes that from a testdata location.
+// If namespace is empty, the namespace created by CreateTestNamespace is used. The files are rendered as Go
+// templates with the values of shared.TemplateValues.
 func DeleteTestData(namespace, folder, pattern string) features.Func {
 	return func(ctx context.Context, t *testing.T, c *envconf.Config) context.Context {
 		t.Helper()
@@ -26,11 +29,7 @@ func DeleteTestData(namespace, folder, pattern string) features.Func {
 			t.Fail()
 		}
 
-		if err := decoder.DecodeEachFile(
-			ctx, os.DirFS(folder), pattern,
-			decoder.DeleteHandler(r),
-			decoder.MutateNamespace(namespace),
-		); err != nil {
+		if err := decodeTestData(ctx, shared.NamespaceOrDefault(ctx, namespace), folder, pattern, decoder.DeleteHandler(r)); err != nil {
 			t.Fail()
 		}
 
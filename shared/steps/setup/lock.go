@@ -0,0 +1,58 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package setup
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+
+	"github.com/open-component-model/ocm-e2e-framework/shared"
+)
+
+type heldLockKey string
+
+// Lock waits until no other feature holds the named shared resource and locks it for this feature, for
+// example shared.FluxSystemLock. The lock is released when the feature finishes, even if a step fails, or
+// earlier with Unlock.
+func Lock(name string) features.Func {
+	return func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+		t.Helper()
+		t.Logf("waiting for lock %s", name)
+
+		shared.LockResource(name)
+
+		t.Logf("acquired lock %s", name)
+
+		// teardown steps are skipped if a step fails, so the cleanup makes sure other features don't wait forever
+		release := sync.OnceFunc(func() {
+			shared.UnlockResource(name)
+		})
+		t.Cleanup(release)
+
+		return context.WithValue(ctx, heldLockKey(name), release)
+	}
+}
+
+// Unlock releases the named shared resource if this feature locked it with Lock and didn't release it yet.
+func Unlock(name string) features.Func {
+	return func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+		t.Helper()
+
+		release, ok := ctx.Value(heldLockKey(name)).(func())
+		if !ok {
+			return ctx
+		}
+
+		release()
+
+		t.Logf("released lock %s", name)
+
+		return ctx
+	}
+}
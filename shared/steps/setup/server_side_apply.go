@@ -0,0 +1,145 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package setup
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	runclient "github.com/fluxcd/pkg/runtime/client"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/e2e-framework/klient/k8s"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+
+	"github.com/open-component-model/ocm-e2e-framework/internal/utils"
+	"github.com/open-component-model/ocm-e2e-framework/shared"
+)
+
+const (
+	applyQueriesPerSecond = 50.0
+	applyBurst            = 300
+)
+
+// ApplyOption configures ServerSideApply and ServerSideApplyObjects.
+type ApplyOption func(*applyOptions)
+
+type applyOptions struct {
+	wait    bool
+	timeout time.Duration
+}
+
+// WithApplyWait waits until all applied objects are ready, as determined by kstatus, for example until a
+// Deployment is available or a Flux object has the Ready condition.
+func WithApplyWait(timeout time.Duration) ApplyOption {
+	return func(o *applyOptions) {
+		o.wait = true
+		o.timeout = timeout
+	}
+}
+
+// ServerSideApply takes a pattern and applies that from a testdata location with server-side apply. Unlike
+// ApplyTestData, objects that already exist are updated, so changed testdata can be applied mid-test.
+// If namespace is empty, the namespace created by CreateTestNamespace is used. The files are rendered as Go
+// templates like for ApplyTestData.
+func ServerSideApply(namespace, folder, pattern string, opts ...ApplyOption) features.Func {
+	return func(ctx context.Context, t *testing.T, c *envconf.Config) context.Context {
+		t.Helper()
+
+		var objects []k8s.Object
+
+		if err := decodeTestData(ctx, shared.NamespaceOrDefault(ctx, namespace), folder, pattern,
+			func(_ context.Context, obj k8s.Object) error {
+				objects = append(objects, obj)
+
+				return nil
+			},
+		); err != nil {
+			t.Fatal(err)
+		}
+
+		return ServerSideApplyObjects(objects, opts...)(ctx, t, c)
+	}
+}
+
+// ServerSideApplyObjects applies the given objects with server-side apply. The objects can be typed objects
+// of a kind registered in the scheme of the client, see AddScheme, or unstructured objects.
+func ServerSideApplyObjects(objects []k8s.Object, opts ...ApplyOption) features.Func {
+	return func(ctx context.Context, t *testing.T, c *envconf.Config) context.Context {
+		t.Helper()
+		t.Log("applying objects with server-side apply...")
+
+		options := &applyOptions{}
+		for _, opt := range opts {
+			opt(options)
+		}
+
+		objs := make([]*unstructured.Unstructured, 0, len(objects))
+
+		for _, obj := range objects {
+			u, err := toUnstructured(obj, c.Client().Resources().GetScheme())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			objs = append(objs, u)
+		}
+
+		kubeConfig := c.KubeconfigFile()
+		kfg := &genericclioptions.ConfigFlags{KubeConfig: &kubeConfig}
+
+		if kubeContext := c.KubeContext(); kubeContext != "" {
+			kfg.Context = &kubeContext
+		}
+
+		runOpts := &runclient.Options{
+			QPS:   applyQueriesPerSecond,
+			Burst: applyBurst,
+		}
+
+		changeSet, err := utils.ApplyObjects(ctx, kfg, runOpts, objs)
+		if err != nil {
+			t.Fatal(fmt.Errorf("server-side apply failed: %w", err))
+		}
+
+		t.Logf("applied objects:\n%s", changeSet.String())
+
+		if options.wait {
+			if err := utils.WaitForChangeSet(kfg, runOpts, changeSet, options.timeout); err != nil {
+				t.Fatal(fmt.Errorf("applied objects didn't become ready: %w", err))
+			}
+		}
+
+		t.Log("server-side apply complete")
+
+		return ctx
+	}
+}
+
+func toUnstructured(obj k8s.Object, scheme *runtime.Scheme) (*unstructured.Unstructured, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return u.DeepCopy(), nil
+	}
+
+	gvk, err := apiutil.GVKForObject(obj, scheme)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find kind of %s: %w", obj.GetName(), err)
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert %s: %w", obj.GetName(), err)
+	}
+
+	u := &unstructured.Unstructured{Object: content}
+	u.SetGroupVersionKind(gvk)
+
+	return u, nil
+}
@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package setup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"testing"
+
+	"sigs.k8s.io/e2e-framework/klient/decoder"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+	"sigs.k8s.io/e2e-framework/pkg/features"
+
+	"github.com/open-component-model/ocm-e2e-framework/shared"
+)
+
+// SetTemplateValues adds values for templated testdata to the context, for example a repository name created
+// with shared.UniqueName, a component version or the name of a generated secret. See shared.TemplateValues.
+func SetTemplateValues(values map[string]any) features.Func {
+	return func(ctx context.Context, t *testing.T, _ *envconf.Config) context.Context {
+		t.Helper()
+
+		return shared.WithTemplateValues(ctx, values)
+	}
+}
+
+// decodeTestData renders the files matching pattern in folder as templates with the values from the context
+// and decodes the objects in them, setting their namespace.
+func decodeTestData(ctx context.Context, namespace, folder, pattern string, handler decoder.HandlerFunc) error {
+	fsys := os.DirFS(folder)
+
+	files, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return fmt.Errorf("failed to list test data: %w", err)
+	}
+
+	values := shared.TemplateValues(ctx, namespace)
+
+	for _, file := range files {
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return fmt.Errorf("failed to read test data %s: %w", file, err)
+		}
+
+		rendered, err := shared.RenderTemplate(file, content, values)
+		if err != nil {
+			return err
+		}
+
+		if err := decoder.DecodeEach(ctx, bytes.NewReader(rendered), handler, decoder.MutateNamespace(namespace)); err != nil {
+			return fmt.Errorf("failed to decode test data %s: %w", file, err)
+		}
+	}
+
+	return nil
+}
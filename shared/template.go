@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package shared
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/template"
+)
+
+type templateValuesKey struct{}
+
+// WithTemplateValues returns a context that carries the given values for templated testdata, in addition to
+// the values already in ctx. A value replaces an earlier value with the same key.
+func WithTemplateValues(ctx context.Context, values map[string]any) context.Context {
+	merged := map[string]any{}
+
+	if existing, ok := ctx.Value(templateValuesKey{}).(map[string]any); ok {
+		for k, v := range existing {
+			merged[k] = v
+		}
+	}
+
+	for k, v := range values {
+		merged[k] = v
+	}
+
+	return context.WithValue(ctx, templateValuesKey{}, merged)
+}
+
+// TemplateValues returns the values testdata templates are rendered with. Besides the values set with
+// WithTemplateValues, it contains:
+//
+//   - Namespace: the namespace the testdata is applied to
+//   - SystemNamespace: the namespace of the controllers and Gitea, see WithNamespace
+//   - Registry: the in-cluster address of the registry of the ocm-controller
+//   - LocalRegistry: the address of the registry port forward on the host
+//   - GiteaURL: the in-cluster URL of Gitea
+//   - Owner: the owner of the Gitea test repositories
+func TemplateValues(ctx context.Context, namespace string) map[string]any {
+	values := map[string]any{
+		"Namespace":       namespace,
+		"SystemNamespace": DefaultNamespace,
+		"LocalRegistry":   fmt.Sprintf("127.0.0.1:%d", registryPort),
+		"Owner":           Owner,
+	}
+
+	if address, err := PortForwardAddress(ctx, "registry"); err == nil {
+		values["LocalRegistry"] = address
+	}
+
+	if existing, ok := ctx.Value(templateValuesKey{}).(map[string]any); ok {
+		for k, v := range existing {
+			values[k] = v
+		}
+	}
+
+	if _, ok := values["Registry"]; !ok {
+		values["Registry"] = fmt.Sprintf("registry.%s.svc.cluster.local:%d", values["SystemNamespace"], registryPort)
+	}
+
+	if _, ok := values["GiteaURL"]; !ok {
+		values["GiteaURL"] = fmt.Sprintf("http://gitea.%s.svc.cluster.local:%d", values["SystemNamespace"], giteaPort)
+	}
+
+	return values
+}
+
+// RenderTemplate renders content as a Go template with the given values. Referencing a missing value is an
+// error, so typos in testdata don't go unnoticed.
+func RenderTemplate(name string, content []byte, values map[string]any) ([]byte, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, values); err != nil {
+		return nil, fmt.Errorf("failed to render template %s: %w", name, err)
+	}
+
+	return out.Bytes(), nil
+}
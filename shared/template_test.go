@@ -0,0 +1,157 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package shared
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateValues(t *testing.T) {
+	defaults := map[string]any{
+		"Namespace":       "test-ns",
+		"SystemNamespace": "ocm-system",
+		"LocalRegistry":   "127.0.0.1:5000",
+		"Owner":           Owner,
+		"Registry":        "registry.ocm-system.svc.cluster.local:5000",
+		"GiteaURL":        "http://gitea.ocm-system.svc.cluster.local:3000",
+	}
+
+	withDefaults := func(values map[string]any) map[string]any {
+		merged := map[string]any{}
+		for k, v := range defaults {
+			merged[k] = v
+		}
+
+		for k, v := range values {
+			merged[k] = v
+		}
+
+		return merged
+	}
+
+	registryForward := func(ctx context.Context) context.Context {
+		ctx, err := registerPortForward(ctx, NewPortForwarder("registry", nil, RegistryPortForward().Target, 15000))
+		require.NoError(t, err)
+
+		return ctx
+	}
+
+	tests := []struct {
+		name     string
+		ctx      func(ctx context.Context) context.Context
+		expected map[string]any
+	}{
+		{
+			name:     "defaults",
+			ctx:      func(ctx context.Context) context.Context { return ctx },
+			expected: defaults,
+		},
+		{
+			name: "system namespace changes the in-cluster addresses",
+			ctx: func(ctx context.Context) context.Context {
+				return WithTemplateValues(ctx, map[string]any{"SystemNamespace": "custom-system"})
+			},
+			expected: withDefaults(map[string]any{
+				"SystemNamespace": "custom-system",
+				"Registry":        "registry.custom-system.svc.cluster.local:5000",
+				"GiteaURL":        "http://gitea.custom-system.svc.cluster.local:3000",
+			}),
+		},
+		{
+			name: "values override defaults",
+			ctx: func(ctx context.Context) context.Context {
+				return WithTemplateValues(ctx, map[string]any{
+					"Namespace": "other-ns",
+					"Registry":  "registry.example.com",
+					"GiteaURL":  "http://gitea.example.com",
+				})
+			},
+			expected: withDefaults(map[string]any{
+				"Namespace": "other-ns",
+				"Registry":  "registry.example.com",
+				"GiteaURL":  "http://gitea.example.com",
+			}),
+		},
+		{
+			name: "later values replace earlier ones",
+			ctx: func(ctx context.Context) context.Context {
+				ctx = WithTemplateValues(ctx, map[string]any{"Repository": "podinfo-1", "Version": "v6.3.5"})
+
+				return WithTemplateValues(ctx, map[string]any{"Version": "v6.3.6"})
+			},
+			expected: withDefaults(map[string]any{"Repository": "podinfo-1", "Version": "v6.3.6"}),
+		},
+		{
+			name:     "registry port forward",
+			ctx:      registryForward,
+			expected: withDefaults(map[string]any{"LocalRegistry": "127.0.0.1:15000"}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, TemplateValues(tt.ctx(context.Background()), "test-ns"))
+		})
+	}
+}
+
+func TestWithTemplateValuesDoesNotModifyParent(t *testing.T) {
+	parent := WithTemplateValues(context.Background(), map[string]any{"Version": "v1"})
+	_ = WithTemplateValues(parent, map[string]any{"Version": "v2"})
+
+	assert.Equal(t, "v1", TemplateValues(parent, "")["Version"])
+}
+
+func TestRenderTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		values   map[string]any
+		expected string
+		wantErr  string
+	}{
+		{
+			name:     "values",
+			content:  "namespace: {{ .Namespace }}\nurl: {{ .GiteaURL }}/{{ .Owner }}/{{ .Repository }}\n",
+			values:   map[string]any{"Namespace": "ns", "GiteaURL": "http://gitea:3000", "Owner": "e2e", "Repository": "repo"},
+			expected: "namespace: ns\nurl: http://gitea:3000/e2e/repo\n",
+		},
+		{
+			name:     "without template actions",
+			content:  "kind: ConfigMap\n",
+			expected: "kind: ConfigMap\n",
+		},
+		{
+			name:    "missing value",
+			content: "version: {{ .Version }}\n",
+			values:  map[string]any{"Namespace": "ns"},
+			wantErr: `map has no entry for key "Version"`,
+		},
+		{
+			name:    "invalid template",
+			content: "version: {{ .Version\n",
+			wantErr: "failed to parse template testdata.yaml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := RenderTemplate("testdata.yaml", []byte(tt.content), tt.values)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(result))
+		})
+	}
+}
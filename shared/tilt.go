@@ -6,7 +6,9 @@ package shared
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -21,12 +23,51 @@ const (
 	defaultTimeoutSeconds = 600
 )
 
+var (
+	tiltTimeoutFlag = flag.Duration("tilt-timeout", defaultTimeoutSeconds*time.Second, "timeout of tilt ci")
+	tiltLogDirFlag  = flag.String("tilt-log-dir", "", "directory for the tilt ci logs, the temp directory is used if empty")
+	tiltVerboseFlag = flag.Bool("tilt-verbose", false, "stream the tilt ci output to the test output")
+)
+
+// TiltOptions configures how tilt ci is run by RunTiltForControllersWithOptions.
+type TiltOptions struct {
+	// Timeout of tilt ci. Defaults to the -tilt-timeout flag.
+	Timeout time.Duration
+	// LogDir is the directory the output of each run is written to. Defaults to the -tilt-log-dir flag or
+	// the temp directory.
+	LogDir string
+	// Output receives the output as well, for example os.Stdout. Defaults to os.Stdout if -tilt-verbose is set.
+	Output io.Writer
+	// ControllerPaths maps controller names to the folders containing their Tiltfile. They take precedence
+	// over all other ways of finding a controller, see resolveController.
+	ControllerPaths map[string]string
+	// WorkspaceConfig is the path of a workspace config file. Defaults to the -workspace-config flag,
+	// OCM_E2E_WORKSPACE_CONFIG or the first ocm-e2e-workspace.yaml found in the parent folders.
+	WorkspaceConfig string
+}
+
+// TiltOptionsFromFlags returns the TiltOptions selected by the -tilt-timeout, -tilt-log-dir, -tilt-verbose and
+// -workspace-config flags. It has to be called after the flags were parsed by envconf.NewFromFlags.
+func TiltOptionsFromFlags() TiltOptions {
+	opts := TiltOptions{
+		Timeout:         *tiltTimeoutFlag,
+		LogDir:          *tiltLogDirFlag,
+		WorkspaceConfig: valueOrEnv(*workspaceConfigFlag, WorkspaceConfigEnv),
+	}
+
+	if *tiltVerboseFlag {
+		opts.Output = os.Stdout
+	}
+
+	return opts
+}
+
 // starts from dir and tries finding the controller by stepping outside
-// until root is reached.
+// until root is reached. Only folders containing a Tiltfile are considered.
 func lookForController(name string, dir string) (string, error) {
 	separatorIndex := strings.LastIndex(dir, "/")
 	for separatorIndex > 0 {
-		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+		if err := validateTiltfile(filepath.Join(dir, name)); err == nil {
 			return filepath.Join(dir, name), nil
 		}
 
@@ -37,11 +78,23 @@ func lookForController(name string, dir string) (string, error) {
 	return "", fmt.Errorf("failed to find controller %s", name)
 }
 
-// RunTiltForControllers executes tilt for a list of controllers.
+// RunTiltForControllers executes tilt for a list of controllers with the TiltOptions selected by the flags.
 func RunTiltForControllers(controllers ...string) env.Func {
 	return func(ctx context.Context, c *envconf.Config) (context.Context, error) {
+		return RunTiltForControllersWithOptions(TiltOptionsFromFlags(), controllers...)(ctx, c)
+	}
+}
+
+// RunTiltForControllersWithOptions executes tilt for a list of controllers. The output is written to a log
+// file per run. If tilt fails, the error names the failed resource and contains its last log lines.
+func RunTiltForControllersWithOptions(opts TiltOptions, controllers ...string) env.Func {
+	return func(ctx context.Context, c *envconf.Config) (context.Context, error) {
+		if opts.Timeout == 0 {
+			opts.Timeout = defaultTimeoutSeconds * time.Second
+		}
+
 		tiltFile := ""
-		tctx, cancel := context.WithTimeout(ctx, defaultTimeoutSeconds*time.Second)
+		tctx, cancel := context.WithTimeout(ctx, opts.Timeout)
 
 		defer cancel()
 
@@ -51,9 +104,9 @@ func RunTiltForControllers(controllers ...string) env.Func {
 		}
 
 		for _, controller := range controllers {
-			path, err := lookForController(controller, dir)
+			path, err := resolveController(controller, opts, dir)
 			if err != nil {
-				return ctx, fmt.Errorf("controller with name %q not found", controller)
+				return ctx, err
 			}
 
 			tiltFile += fmt.Sprintf("include('%s/Tiltfile')\n", path)
@@ -71,16 +124,64 @@ func RunTiltForControllers(controllers ...string) env.Func {
 			return ctx, fmt.Errorf("failed to create tilt file %w", err)
 		}
 
-		cmd := exec.CommandContext(tctx, "tilt", "ci")
+		logFile, err := createTiltLogFile(opts.LogDir)
+		if err != nil {
+			return ctx, err
+		}
+
+		defer logFile.Close()
+
+		fmt.Printf("writing tilt output to %s\n", logFile.Name())
+
+		args := []string{"ci"}
+		if c.KubeContext() != "" {
+			args = append(args, "--context", c.KubeContext())
+		}
+
+		cmd := exec.CommandContext(tctx, "tilt", args...)
 		cmd.Dir = temp
 
-		output, err := cmd.CombinedOutput()
-		if err != nil {
-			fmt.Println("output from tilt: ", string(output))
+		if c.KubeconfigFile() != "" {
+			cmd.Env = append(os.Environ(), "KUBECONFIG="+c.KubeconfigFile())
+		}
 
-			return ctx, err
+		tiltOutput := newTiltLog()
+		writers := []io.Writer{logFile, tiltOutput}
+
+		if opts.Output != nil {
+			writers = append(writers, opts.Output)
+		}
+
+		output := io.MultiWriter(writers...)
+		cmd.Stdout = output
+		cmd.Stderr = output
+
+		if err := cmd.Run(); err != nil {
+			if tctx.Err() != nil {
+				err = fmt.Errorf("tilt ci timed out after %s: %w", opts.Timeout, err)
+			}
+
+			return ctx, fmt.Errorf("%w\n%s\nfull output in %s", err, tiltOutput.Summary(), logFile.Name())
 		}
 
 		return ctx, nil
 	}
 }
+
+func createTiltLogFile(dir string) (*os.File, error) {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	var logDirPermMod os.FileMode = 0o755
+	if err := os.MkdirAll(dir, logDirPermMod); err != nil {
+		return nil, fmt.Errorf("failed to create tilt log directory: %w", err)
+	}
+
+	file, err := os.CreateTemp(dir, fmt.Sprintf("tilt-%s-*.log", time.Now().Format("20060102-150405")))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tilt log file: %w", err)
+	}
+
+	return file, nil
+}
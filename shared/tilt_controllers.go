@@ -0,0 +1,151 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package shared
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// WorkspaceConfigEnv points to a workspace config file if the -workspace-config flag isn't set.
+	WorkspaceConfigEnv = "OCM_E2E_WORKSPACE_CONFIG"
+	// WorkspaceConfigFile is the name of the workspace config file that is searched in the parent folders.
+	WorkspaceConfigFile = "ocm-e2e-workspace.yaml"
+
+	controllerPathEnvSuffix = "_PATH"
+)
+
+var workspaceConfigFlag = flag.String("workspace-config", "",
+	fmt.Sprintf("workspace config file with the paths of the controllers (env %s)", WorkspaceConfigEnv))
+
+// WorkspaceConfig is the content of a workspace config file. It maps controller names to the folders
+// containing their Tiltfile. Relative paths are relative to the config file:
+//
+//	controllers:
+//	  ocm-controller: ../ocm-controller
+//	  git-controller: /src/git-controller
+type WorkspaceConfig struct {
+	Controllers map[string]string `json:"controllers"`
+}
+
+// ControllerPathEnv returns the environment variable that overrides the path of a controller, for example
+// OCM_CONTROLLER_PATH for ocm-controller.
+func ControllerPathEnv(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + controllerPathEnvSuffix
+}
+
+// resolveController returns the folder containing the Tiltfile of a controller. It's looked up in order in
+// TiltOptions.ControllerPaths, the environment variable named by ControllerPathEnv, the workspace config file
+// and finally the parent folders of dir. A configured path without a Tiltfile is an error.
+func resolveController(name string, opts TiltOptions, dir string) (string, error) {
+	if path, ok := opts.ControllerPaths[name]; ok {
+		return validatedControllerPath(name, path, "explicit path")
+	}
+
+	if path := os.Getenv(ControllerPathEnv(name)); path != "" {
+		return validatedControllerPath(name, path, "environment variable "+ControllerPathEnv(name))
+	}
+
+	configPath := opts.WorkspaceConfig
+	if configPath == "" {
+		configPath = findWorkspaceConfig(dir)
+	}
+
+	if configPath != "" {
+		config, err := readWorkspaceConfig(configPath)
+		if err != nil {
+			return "", err
+		}
+
+		if path, ok := config.Controllers[name]; ok {
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(filepath.Dir(configPath), path)
+			}
+
+			return validatedControllerPath(name, path, "workspace config "+configPath)
+		}
+	}
+
+	path, err := lookForController(name, dir)
+	if err != nil {
+		return "", fmt.Errorf("controller with name %q not found, set %s or add it to %s: %w",
+			name, ControllerPathEnv(name), WorkspaceConfigFile, err)
+	}
+
+	fmt.Printf("resolved controller %s to %s from the parent folders of %s\n", name, path, dir)
+
+	return path, nil
+}
+
+func validatedControllerPath(name, path, source string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve path of controller %s: %w", name, err)
+	}
+
+	if err := validateTiltfile(path); err != nil {
+		return "", fmt.Errorf("controller %s from %s: %w", name, source, err)
+	}
+
+	fmt.Printf("resolved controller %s to %s from %s\n", name, path, source)
+
+	return path, nil
+}
+
+// validateTiltfile checks that dir contains a Tiltfile.
+func validateTiltfile(dir string) error {
+	info, err := os.Stat(filepath.Join(dir, "Tiltfile"))
+	if err != nil {
+		return fmt.Errorf("no Tiltfile found in %s: %w", dir, err)
+	}
+
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", filepath.Join(dir, "Tiltfile"))
+	}
+
+	return nil
+}
+
+// findWorkspaceConfig returns the first workspace config file in dir or its parents, or an empty string.
+func findWorkspaceConfig(dir string) string {
+	for {
+		path := filepath.Join(dir, WorkspaceConfigFile)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+
+		dir = parent
+	}
+}
+
+func readWorkspaceConfig(path string) (*WorkspaceConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("workspace config %s not found: %w", path, err)
+		}
+
+		return nil, fmt.Errorf("failed to read workspace config %s: %w", path, err)
+	}
+
+	config := &WorkspaceConfig{}
+	if err := yaml.Unmarshal(content, config); err != nil {
+		return nil, fmt.Errorf("failed to parse workspace config %s: %w", path, err)
+	}
+
+	return config, nil
+}
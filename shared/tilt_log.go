@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package shared
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	// tiltSummaryLines is the number of log lines of the failed resource included in the summary.
+	tiltSummaryLines = 20
+	// tiltResourceSeparator separates the resource name from the log line in the output of tilt ci.
+	tiltResourceSeparator = "│"
+)
+
+// tiltLog parses the output of tilt ci. It keeps the last lines logged by every resource and the error
+// lines, so a failure can be summarized.
+type tiltLog struct {
+	mu        sync.Mutex
+	partial   []byte
+	resources map[string][]string
+	order     []string
+	errors    []string
+}
+
+func newTiltLog() *tiltLog {
+	return &tiltLog{resources: map[string][]string{}}
+}
+
+// Write implements io.Writer. It's safe to use for stdout and stderr at the same time.
+func (l *tiltLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.partial = append(l.partial, p...)
+
+	for {
+		i := bytes.IndexByte(l.partial, '\n')
+		if i < 0 {
+			break
+		}
+
+		l.parseLine(string(l.partial[:i]))
+		l.partial = l.partial[i+1:]
+	}
+
+	return len(p), nil
+}
+
+func (l *tiltLog) parseLine(line string) {
+	line = strings.TrimRight(line, "\r")
+
+	if resource, message, ok := strings.Cut(line, tiltResourceSeparator); ok {
+		resource = strings.TrimSpace(resource)
+		if resource != "" && !strings.ContainsAny(resource, " \t") {
+			if _, seen := l.resources[resource]; !seen {
+				l.order = append(l.order, resource)
+			}
+
+			lines := append(l.resources[resource], strings.TrimPrefix(message, " "))
+			if len(lines) > tiltSummaryLines {
+				lines = lines[len(lines)-tiltSummaryLines:]
+			}
+
+			l.resources[resource] = lines
+
+			return
+		}
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(line), "Error:") {
+		l.errors = append(l.errors, strings.TrimSpace(line))
+	}
+}
+
+// failedResource returns the resource named in the error lines of tilt ci. If no resource is named, it
+// falls back to the last resource that logged an error.
+func (l *tiltLog) failedResource() string {
+	for _, line := range l.errors {
+		for _, resource := range l.order {
+			if strings.Contains(line, resource) {
+				return resource
+			}
+		}
+	}
+
+	for i := len(l.order) - 1; i >= 0; i-- {
+		for _, line := range l.resources[l.order[i]] {
+			if strings.Contains(strings.ToLower(line), "error") {
+				return l.order[i]
+			}
+		}
+	}
+
+	return ""
+}
+
+// Summary describes why tilt ci failed: its error lines, the failed resource and that resource's last log lines.
+func (l *tiltLog) Summary() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.partial) > 0 {
+		l.parseLine(string(l.partial))
+		l.partial = nil
+	}
+
+	var b strings.Builder
+
+	for _, line := range l.errors {
+		b.WriteString(line + "\n")
+	}
+
+	resource := l.failedResource()
+	if resource == "" {
+		b.WriteString("no failed tilt resource found")
+
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "failed tilt resource %s, last log lines:\n", resource)
+
+	for _, line := range l.resources[resource] {
+		b.WriteString("  " + line + "\n")
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
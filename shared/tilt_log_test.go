@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package shared
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTiltLogSummary(t *testing.T) {
+	var longLog strings.Builder
+	for i := 1; i <= tiltSummaryLines+5; i++ {
+		fmt.Fprintf(&longLog, "ocm-controller │ line %d\n", i)
+	}
+
+	var lastLines strings.Builder
+	for i := 6; i <= tiltSummaryLines+5; i++ {
+		fmt.Fprintf(&lastLines, "\n  line %d", i)
+	}
+
+	tests := []struct {
+		name     string
+		writes   []string
+		expected string
+	}{
+		{
+			name: "resource named in error line",
+			writes: []string{
+				"git-controller │ starting\n",
+				"ocm-controller │ Building Dockerfile\n",
+				"ocm-controller │ failed to build: exit code 1\n",
+				"Error: Build Failed: ocm-controller\n",
+			},
+			expected: "Error: Build Failed: ocm-controller\n" +
+				"failed tilt resource ocm-controller, last log lines:\n" +
+				"  Building Dockerfile\n" +
+				"  failed to build: exit code 1",
+		},
+		{
+			name: "last resource that logged an error",
+			writes: []string{
+				"git-controller │ error: image pull failed\n",
+				"ocm-controller │ ready\n",
+				"Error: timeout waiting for resources\n",
+			},
+			expected: "Error: timeout waiting for resources\n" +
+				"failed tilt resource git-controller, last log lines:\n" +
+				"  error: image pull failed",
+		},
+		{
+			name: "no failed resource",
+			writes: []string{
+				"Initial Build\n",
+				"ocm-controller │ ready\n",
+			},
+			expected: "no failed tilt resource found",
+		},
+		{
+			name: "lines split across writes, CRLF and unterminated last line",
+			writes: []string{
+				"ocm-contr",
+				"oller │ error: crash\r\nError: Pod",
+				" crashed: ocm-controller",
+			},
+			expected: "Error: Pod crashed: ocm-controller\n" +
+				"failed tilt resource ocm-controller, last log lines:\n" +
+				"  error: crash",
+		},
+		{
+			name:   "only the last lines of a resource are kept",
+			writes: []string{longLog.String(), "Error: ocm-controller failed\n"},
+			expected: "Error: ocm-controller failed\n" +
+				"failed tilt resource ocm-controller, last log lines:" + lastLines.String(),
+		},
+		{
+			name: "prefix with spaces isn't a resource",
+			writes: []string{
+				"some message │ with a separator\n",
+				"Error: something failed\n",
+			},
+			expected: "Error: something failed\nno failed tilt resource found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := newTiltLog()
+
+			for _, w := range tt.writes {
+				n, err := log.Write([]byte(w))
+				assert.NoError(t, err)
+				assert.Equal(t, len(w), n)
+			}
+
+			assert.Equal(t, tt.expected, log.Summary())
+		})
+	}
+}
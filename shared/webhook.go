@@ -0,0 +1,213 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package shared
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/e2e-framework/pkg/env"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+// WebhookEvent is a single request recorded by a WebhookReceiver.
+type WebhookEvent struct {
+	Path     string
+	Header   http.Header
+	Body     []byte
+	Received time.Time
+}
+
+// WebhookReceiver is a host-side HTTP server that records every payload sent to it. It stands in for
+// the endpoints Gitea webhooks and Flux notification-controller providers would normally call.
+type WebhookReceiver struct {
+	// URL is the address under which the receiver is reachable from inside the cluster.
+	URL string
+
+	server *http.Server
+	mu     sync.Mutex
+	events []WebhookEvent
+}
+
+// WebhookReceiverOptions configures a WebhookReceiver.
+type WebhookReceiverOptions struct {
+	// Port is the host port to listen on. If 0, a free port is chosen.
+	Port int
+	// AdvertiseHost is the host name or IP the cluster uses to reach the receiver. If empty, the gateway of
+	// the docker network of a kind or k3d cluster is used. It's required for other cluster providers.
+	AdvertiseHost string
+	// ListenAddress is the host address the receiver listens on. Defaults to AdvertiseHost, so the receiver
+	// isn't exposed on other interfaces. Set it if the advertised host isn't an address of this machine, for
+	// example with Docker Desktop.
+	ListenAddress string
+	// Cluster selects the docker network used to find the advertised host. Defaults to a kind cluster.
+	Cluster ClusterOptions
+}
+
+type webhookReceiverKey string
+
+// StartWebhookReceiver starts a WebhookReceiver and stores it in the context under the given name.
+func StartWebhookReceiver(name string, opts WebhookReceiverOptions) env.Func {
+	return func(ctx context.Context, config *envconf.Config) (context.Context, error) {
+		host := opts.AdvertiseHost
+		if host == "" {
+			gateway, err := clusterNetworkGateway(ctx, opts.Cluster)
+			if err != nil {
+				return ctx, fmt.Errorf("failed to determine address of webhook receiver, set AdvertiseHost: %w", err)
+			}
+
+			host = gateway
+		}
+
+		listenAddress := opts.ListenAddress
+		if listenAddress == "" {
+			listenAddress = host
+		}
+
+		listener, err := net.Listen("tcp", net.JoinHostPort(listenAddress, fmt.Sprintf("%d", opts.Port)))
+		if err != nil {
+			return ctx, fmt.Errorf("failed to listen for webhook receiver %s: %w", name, err)
+		}
+
+		port := listener.Addr().(*net.TCPAddr).Port
+		receiver := &WebhookReceiver{
+			URL: fmt.Sprintf("http://%s", net.JoinHostPort(host, fmt.Sprintf("%d", port))),
+		}
+		receiver.server = &http.Server{
+			Handler:           http.HandlerFunc(receiver.record),
+			ReadHeaderTimeout: time.Second * 10,
+		}
+
+		go func() {
+			if err := receiver.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				fmt.Printf("webhook receiver %s stopped: %s\n", name, err)
+			}
+		}()
+
+		fmt.Printf("webhook receiver %s listening on %s\n", name, receiver.URL)
+
+		return context.WithValue(ctx, webhookReceiverKey(name), receiver), nil
+	}
+}
+
+// StopWebhookReceiver shuts down the WebhookReceiver with the given name.
+func StopWebhookReceiver(name string) env.Func {
+	return func(ctx context.Context, config *envconf.Config) (context.Context, error) {
+		receiver, err := WebhookReceiverFromContext(ctx, name)
+		if err != nil {
+			return ctx, err
+		}
+
+		sctx, cancel := context.WithTimeout(ctx, time.Second*10)
+		defer cancel()
+
+		if err := receiver.server.Shutdown(sctx); err != nil {
+			return ctx, fmt.Errorf("failed to stop webhook receiver %s: %w", name, err)
+		}
+
+		return ctx, nil
+	}
+}
+
+// WebhookReceiverFromContext returns the WebhookReceiver started with the given name.
+func WebhookReceiverFromContext(ctx context.Context, name string) (*WebhookReceiver, error) {
+	receiver, ok := ctx.Value(webhookReceiverKey(name)).(*WebhookReceiver)
+	if !ok {
+		return nil, fmt.Errorf("webhook receiver %s not found in context", name)
+	}
+
+	return receiver, nil
+}
+
+// Events returns a copy of all recorded events.
+func (r *WebhookReceiver) Events() []WebhookEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := make([]WebhookEvent, len(r.events))
+	copy(events, r.events)
+
+	return events
+}
+
+// Find returns the first recorded event that matches.
+func (r *WebhookReceiver) Find(match func(event WebhookEvent) bool) (WebhookEvent, bool) {
+	for _, event := range r.Events() {
+		if match(event) {
+			return event, true
+		}
+	}
+
+	return WebhookEvent{}, false
+}
+
+// Reset drops all recorded events.
+func (r *WebhookReceiver) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = nil
+}
+
+func (r *WebhookReceiver) record(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	r.mu.Lock()
+	r.events = append(r.events, WebhookEvent{
+		Path:     req.URL.Path,
+		Header:   req.Header.Clone(),
+		Body:     body,
+		Received: time.Now(),
+	})
+	r.mu.Unlock()
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// clusterNetworkGateway returns the gateway of the docker network the nodes of a kind or k3d cluster are
+// attached to, which is the address of the host as seen from inside the cluster.
+func clusterNetworkGateway(ctx context.Context, cluster ClusterOptions) (string, error) {
+	var network string
+
+	switch cluster.Provider {
+	case "", ClusterProviderKind:
+		network = "kind"
+	case ClusterProviderK3d:
+		network = "k3d-" + cluster.Name
+	default:
+		return "", fmt.Errorf("the host address can't be determined for cluster provider %s", cluster.Provider)
+	}
+
+	return dockerNetworkGateway(ctx, network)
+}
+
+func dockerNetworkGateway(ctx context.Context, network string) (string, error) {
+	output, err := exec.CommandContext(ctx, "docker", "network", "inspect", network,
+		"--format", "{{range .IPAM.Config}}{{.Gateway}} {{end}}").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect docker network %s: %w", network, err)
+	}
+
+	for _, gateway := range strings.Fields(string(output)) {
+		if ip := net.ParseIP(gateway); ip != nil && ip.To4() != nil {
+			return gateway, nil
+		}
+	}
+
+	return "", fmt.Errorf("no IPv4 gateway found for docker network %s", network)
+}
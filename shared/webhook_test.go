@@ -0,0 +1,45 @@
+// SPDX-FileCopyrightText: 2022 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package shared
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/e2e-framework/pkg/envconf"
+)
+
+func TestWebhookReceiver(t *testing.T) {
+	ctx, err := StartWebhookReceiver("test", WebhookReceiverOptions{AdvertiseHost: "127.0.0.1"})(context.Background(), envconf.New())
+	require.NoError(t, err)
+
+	receiver, err := WebhookReceiverFromContext(ctx, "test")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(receiver.URL, "http://127.0.0.1:"))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, receiver.URL+"/alerts", strings.NewReader(`{"reason":"test"}`))
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	event, ok := receiver.Find(func(event WebhookEvent) bool { return event.Path == "/alerts" })
+	require.True(t, ok)
+	assert.Equal(t, `{"reason":"test"}`, string(event.Body))
+
+	_, err = StopWebhookReceiver("test")(ctx, envconf.New())
+	require.NoError(t, err)
+}
+
+func TestClusterNetworkGatewayRequiresKindOrK3d(t *testing.T) {
+	_, err := clusterNetworkGateway(context.Background(), ClusterOptions{Provider: ClusterProviderExisting})
+	assert.ErrorContains(t, err, "can't be determined for cluster provider existing")
+}
@@ -5,42 +5,32 @@
 package componentversion
 
 import (
+	"fmt"
 	"os"
 	"testing"
 
 	"sigs.k8s.io/e2e-framework/pkg/env"
-	"sigs.k8s.io/e2e-framework/pkg/envconf"
-	"sigs.k8s.io/e2e-framework/pkg/envfuncs"
 
 	"github.com/open-component-model/ocm-e2e-framework/shared"
 )
 
 var (
-	testEnv         env.Environment
-	kindClusterName string
-	namespace       string
+	testEnv   env.Environment
+	namespace = shared.DefaultNamespace
 )
 
 func TestMain(m *testing.M) {
-	cfg, _ := envconf.NewFromFlags()
-	testEnv = env.NewWithConfig(cfg)
-	kindClusterName = envconf.RandomName("component-version", 32)
-	namespace = "ocm-system"
-
-	stopChannel := make(chan struct{}, 1)
-
-	testEnv.Setup(
-		envfuncs.CreateKindCluster(kindClusterName),
-		envfuncs.CreateNamespace(namespace),
-		shared.RunTiltForControllers("ocm-controller", "replication-controller"),
-		shared.ForwardPortForAppName("registry", 5000, stopChannel),
-	)
+	var err error
 
-	testEnv.Finish(
-		shared.ShutdownPortForward(stopChannel),
-		envfuncs.DeleteNamespace(namespace),
-		envfuncs.DestroyKindCluster(kindClusterName),
+	testEnv, err = shared.NewEnvironment("component-version",
+		shared.WithNamespace(namespace),
+		shared.WithControllers("ocm-controller", "replication-controller"),
+		shared.WithPortForwards(shared.RegistryPortForward()),
 	)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
 	os.Exit(testEnv.Run(m))
 }
@@ -5,47 +5,33 @@
 package gitsync
 
 import (
+	"fmt"
 	"os"
 	"testing"
 
 	"sigs.k8s.io/e2e-framework/pkg/env"
-	"sigs.k8s.io/e2e-framework/pkg/envconf"
-	"sigs.k8s.io/e2e-framework/pkg/envfuncs"
 
 	"github.com/open-component-model/ocm-e2e-framework/shared"
 )
 
 var (
-	testEnv         env.Environment
-	kindClusterName string
-	namespace       string
+	testEnv   env.Environment
+	namespace = shared.DefaultNamespace
 )
 
 func TestMain(m *testing.M) {
-	cfg, _ := envconf.NewFromFlags()
-	testEnv = env.NewWithConfig(cfg)
-	kindClusterName = envconf.RandomName("git", 32)
-	namespace = "ocm-system"
-
-	stopChannelRegistry := make(chan struct{}, 1)
-	stopChannelGitea := make(chan struct{}, 1)
-
-	testEnv.Setup(
-		envfuncs.CreateKindCluster(kindClusterName),
-		envfuncs.CreateNamespace(namespace),
-		shared.StartGitServer(namespace),
-		shared.RunTiltForControllers("ocm-controller", "git-controller"),
-		shared.ForwardPortForAppName("registry", 5000, stopChannelRegistry),
-		shared.ForwardPortForAppName("gitea", 3000, stopChannelGitea),
-	)
+	var err error
 
-	testEnv.Finish(
-		shared.RemoveGitServer(namespace),
-		shared.ShutdownPortForward(stopChannelRegistry),
-		shared.ShutdownPortForward(stopChannelGitea),
-		envfuncs.DeleteNamespace(namespace),
-		envfuncs.DestroyKindCluster(kindClusterName),
+	testEnv, err = shared.NewEnvironment("git",
+		shared.WithNamespace(namespace),
+		shared.WithGitea(),
+		shared.WithControllers("ocm-controller", "git-controller"),
+		shared.WithPortForwards(shared.RegistryPortForward(), shared.GiteaPortForward()),
 	)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
 	os.Exit(testEnv.Run(m))
 }